@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/kvizadsaderah/infrasync/pkg/compare"
+	"github.com/kvizadsaderah/infrasync/pkg/formatter"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// runDiffCommand compares two plans and exits with 1 when they differ
+func runDiffCommand(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	outputFormat := flags.String("format", "cli", "Output format: cli, markdown, json")
+	outputFile := flags.String("output", "", "Write output to file instead of stdout")
+	colorFlag := flags.String("color", "auto", "Colored output: auto (terminals, unless NO_COLOR is set), always, never")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [options] <old.json> <new.json>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Shows which changes were added, resolved or planned differently since an earlier plan.\n")
+		fmt.Fprintf(os.Stderr, "Exits with 0 when the plans are equivalent and 1 when they differ.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 1
+	}
+
+	colorMode, ok := formatter.ParseColorMode(*colorFlag)
+	if !ok {
+		color.Red("Unknown color mode: %s", *colorFlag)
+		return 1
+	}
+
+	previous, err := parser.ParsePlanFile(flags.Arg(0))
+	if err != nil {
+		color.Red("Error parsing plan: %v", err)
+		return 1
+	}
+	current, err := parser.ParsePlanFile(flags.Arg(1))
+	if err != nil {
+		color.Red("Error parsing plan: %v", err)
+		return 1
+	}
+
+	result := compare.Plans(previous, current)
+
+	var output bytes.Buffer
+	out := io.Writer(os.Stdout)
+	if *outputFile != "" {
+		out = &output
+	}
+
+	switch *outputFormat {
+	case "cli":
+		f := formatter.NewCLIFormatter(false, false)
+		f.Color = colorMode
+		err = f.FormatComparison(out, result)
+	case "markdown":
+		err = formatter.NewMarkdownFormatter(true, false, false).FormatComparison(out, result)
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(result)
+	default:
+		color.Red("Unknown format: %s", *outputFormat)
+		color.Yellow("Supported formats: cli, markdown, json")
+		return 1
+	}
+	if err != nil {
+		color.Red("Error writing output: %v", err)
+		return 1
+	}
+
+	if *outputFile != "" {
+		if err := os.WriteFile(*outputFile, output.Bytes(), 0644); err != nil {
+			color.Red("Error writing output file: %v", err)
+			return 1
+		}
+		color.Green("✓ Output written to %s", *outputFile)
+	}
+
+	if result.HasDifferences() {
+		return 1
+	}
+	return 0
+}
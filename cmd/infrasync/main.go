@@ -1,13 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
+	"text/template"
 
 	"github.com/fatih/color"
 	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/config"
 	"github.com/kvizadsaderah/infrasync/pkg/formatter"
+	"github.com/kvizadsaderah/infrasync/pkg/infrasync"
+	"github.com/kvizadsaderah/infrasync/pkg/notify"
 	"github.com/kvizadsaderah/infrasync/pkg/parser"
 )
 
@@ -16,18 +25,62 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "policy":
+			os.Exit(runPolicyCommand(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiffCommand(os.Args[2:]))
+		}
+	}
+
 	// Define flags
-	outputFormat := flag.String("format", "cli", "Output format: cli, markdown")
+	outputFormat := flag.String("format", "cli", "Output format: cli, markdown, json, terraform, slack, teams")
 	showVersion := flag.Bool("version", false, "Show version")
 	showUnchanged := flag.Bool("show-unchanged", false, "Show unchanged resources")
 	verbose := flag.Bool("verbose", false, "Verbose output with all attributes")
 	compact := flag.Bool("compact", false, "Compact output")
 	showWarnings := flag.Bool("warnings", true, "Show security and risk warnings")
 	outputFile := flag.String("output", "", "Write output to file instead of stdout")
+	groupBy := flag.String("group-by", "action", "Group the resource list by: action, module, provider, type")
+	importantFirst := flag.Bool("important-first", false, "List the most relevant attributes of each resource type first")
+	colorFlag := flag.String("color", "auto", "Colored output: auto (terminals, unless NO_COLOR is set), always, never")
+	maxLength := flag.Int("max-length", 0, "Fit markdown output into this many characters (0: no limit)")
+	platform := flag.String("platform", "", "Fit markdown output into the comment limit of: "+strings.Join(formatter.Platforms(), ", "))
+	overflowFlag := flag.String("overflow", "split", "Markdown output still too long without details: split into numbered parts, or truncate")
+	artifactURL := flag.String("artifact-url", "", "Full report linked from truncated markdown output and chat messages")
+	templateFile := flag.String("template", "", "Render markdown output with this Go text/template file")
+	terragruntDir := flag.String("terragrunt", "", "Discover the plans of all Terragrunt units under this directory")
+	notifyHooks := flag.Bool("notify", false, "Deliver the report to the webhooks of the configuration file")
+	terraformBin := flag.String("terraform", "terraform", "Terraform executable used to render binary plan files")
+
+	var filter parser.Filter
+	flag.Var((*listFlag)(&filter.Include), "include", "Only report resources whose address matches this glob (repeatable, comma-separated)")
+	flag.Var((*listFlag)(&filter.Exclude), "exclude", "Leave out resources whose address matches this glob (repeatable, comma-separated)")
+	flag.Var((*listFlag)(&filter.Types), "type", "Only report resources of this type or type glob (repeatable, comma-separated)")
+	flag.Var((*listFlag)(&filter.Actions), "action", "Only report create, update, replace, delete, read or no-op changes (repeatable, comma-separated)")
+	flag.Var((*listFlag)(&filter.Modules), "module", "Only report resources in this module and its nested modules (repeatable, comma-separated)")
+
+	lockFile := flag.String("lock-file", "", "Terraform lock file with the provider versions used by the plan")
+	previousPlan := flag.String("previous-plan", "", "Previous plan JSON to compare Terraform and provider versions against")
+	previousLockFile := flag.String("previous-lock-file", "", "Previous lock file to compare provider versions against")
+
+	configFile := flag.String("config", "", "Configuration file (default: "+config.DefaultFile+" if present)")
+	showScore := flag.Bool("score", true, "Show the numeric risk score")
+	regoPath := flag.String("rego", "", "Rego policy file or directory to evaluate against the plan")
+
+	defaults := analyzer.DefaultMassChangeConfig()
+	maxDeleteRatio := flag.Float64("max-delete-ratio", defaults.Plan.DeleteRatio, "Flag plans destroying at least this fraction of existing resources (0 disables)")
+	maxReplaceRatio := flag.Float64("max-replace-ratio", defaults.Plan.ReplaceRatio, "Flag plans replacing at least this fraction of existing resources (0 disables)")
+	maxDeletes := flag.Int("max-deletes", defaults.Plan.Deletes, "Flag plans destroying at least this many resources (0 disables)")
+	maxReplaces := flag.Int("max-replaces", defaults.Plan.Replaces, "Flag plans replacing at least this many resources (0 disables)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "InfraSync - Beautiful Terraform Plan Analysis\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] <tfplan.json>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <tfplan.json> [[name=]tfplan.json | glob ...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] --terragrunt <dir>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff [options] <old.json> <new.json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s policy test [options] [manifest.yml]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -35,8 +88,24 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s tfplan.json\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Generate markdown for GitHub PR comment\n")
 		fmt.Fprintf(os.Stderr, "  %s --format markdown tfplan.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Familiar terraform plan layout with warnings inline\n")
+		fmt.Fprintf(os.Stderr, "  %s --format terraform tfplan.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Slack message payload, to post to an incoming webhook\n")
+		fmt.Fprintf(os.Stderr, "  %s --format slack tfplan.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Post the report to the webhooks of the configuration file\n")
+		fmt.Fprintf(os.Stderr, "  %s --notify tfplan.json\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Save output to file\n")
 		fmt.Fprintf(os.Stderr, "  %s --format markdown --output plan.md tfplan.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # PR comment split into parts that fit GitHub's comment limit\n")
+		fmt.Fprintf(os.Stderr, "  %s --format markdown --platform github tfplan.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # PR comment rendered with a custom template\n")
+		fmt.Fprintf(os.Stderr, "  %s --format markdown --template report.tmpl tfplan.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Combined report for several stacks\n")
+		fmt.Fprintf(os.Stderr, "  %s network=net.json data=data.json 'stacks/*/tfplan.json'\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Report for every unit after terragrunt run-all plan -out=tfplan\n")
+		fmt.Fprintf(os.Stderr, "  %s --terragrunt live/prod\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Focused PR comment for a single module\n")
+		fmt.Fprintf(os.Stderr, "  %s --format markdown --module module.vpc tfplan.json\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Verbose output with all attribute changes\n")
 		fmt.Fprintf(os.Stderr, "  %s --verbose tfplan.json\n\n", os.Args[0])
 	}
@@ -49,178 +118,311 @@ func main() {
 	}
 
 	// Check for plan file argument
-	if flag.NArg() < 1 {
+	if flag.NArg() < 1 && *terragruntDir == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	planFile := flag.Arg(0)
-
-	// Parse the plan
-	summary, err := parser.ParsePlanFile(planFile)
+	plans, err := infrasync.PlanFiles(flag.Args())
 	if err != nil {
-		color.Red("Error parsing plan: %v", err)
+		color.Red("%v", err)
 		os.Exit(1)
 	}
-
-	// Analyze for warnings
-	var warnings []analyzer.Warning
-	if *showWarnings {
-		warnings = analyzer.AnalyzeChanges(summary)
+	if *terragruntDir != "" {
+		discovered, err := infrasync.DiscoverPlans(*terragruntDir)
+		if err != nil {
+			color.Red("%v", err)
+			os.Exit(1)
+		}
+		if len(discovered) == 0 {
+			color.Red("No plan files found under %s", *terragruntDir)
+			os.Exit(1)
+		}
+		plans = append(discovered, plans...)
+	}
+	if len(plans) > 1 && (*lockFile != "" || *previousPlan != "" || *previousLockFile != "") {
+		color.Red("--lock-file, --previous-plan and --previous-lock-file need a single plan")
+		os.Exit(1)
 	}
 
-	// Format and output
-	var output string
+	if !slices.Contains(infrasync.Formats(), *outputFormat) {
+		color.Red("Unknown format: %s", *outputFormat)
+		color.Yellow("Supported formats: %s", strings.Join(infrasync.Formats(), ", "))
+		os.Exit(1)
+	}
 
-	switch *outputFormat {
-	case "cli":
-		cliFormatter := formatter.NewCLIFormatter(*showUnchanged, *verbose)
+	colorMode, ok := formatter.ParseColorMode(*colorFlag)
+	if !ok {
+		color.Red("Unknown color mode: %s", *colorFlag)
+		color.Yellow("Supported color modes: auto, always, never")
+		os.Exit(1)
+	}
 
-		// Print to stdout (this is the visual output)
-		cliFormatter.Format(summary)
+	overflow, ok := formatter.ParseOverflow(*overflowFlag)
+	if !ok {
+		color.Red("Unknown overflow mode: %s", *overflowFlag)
+		color.Yellow("Supported overflow modes: split, truncate")
+		os.Exit(1)
+	}
+	if *platform != "" {
+		limit, ok := formatter.CommentLimit(*platform)
+		if !ok {
+			color.Red("Unknown platform: %s", *platform)
+			color.Yellow("Supported platforms: %s", strings.Join(formatter.Platforms(), ", "))
+			os.Exit(1)
+		}
+		if *maxLength == 0 || limit < *maxLength {
+			*maxLength = limit
+		}
+	}
 
-		// Print warnings
-		if len(warnings) > 0 {
-			printWarningsCLI(warnings)
+	var tmpl *template.Template
+	if *templateFile != "" {
+		if *outputFormat != "markdown" {
+			color.Red("--template needs --format markdown")
+			os.Exit(1)
 		}
+		tmpl, err = formatter.ParseTemplateFile(*templateFile)
+		if err != nil {
+			color.Red("%v", err)
+			os.Exit(1)
+		}
+	}
 
-		// Print general warnings
-		cliFormatter.PrintWarnings(summary)
+	grouping, ok := formatter.ParseGroupBy(*groupBy)
+	if !ok {
+		color.Red("Unknown grouping: %s", *groupBy)
+		color.Yellow("Supported groupings: action, module, provider, type")
+		os.Exit(1)
+	}
 
-		// Exit with appropriate code
-		exitCode := determineExitCode(summary, warnings)
-		os.Exit(exitCode)
+	// Load configuration; explicitly set flags take precedence over the file
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		color.Red("Error loading config: %v", err)
+		os.Exit(1)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-delete-ratio":
+			cfg.MassChange.Plan.DeleteRatio = *maxDeleteRatio
+		case "max-replace-ratio":
+			cfg.MassChange.Plan.ReplaceRatio = *maxReplaceRatio
+		case "max-deletes":
+			cfg.MassChange.Plan.Deletes = *maxDeletes
+		case "max-replaces":
+			cfg.MassChange.Plan.Replaces = *maxReplaces
+		case "important-first":
+			cfg.AttributeOrder.ImportantFirst = *importantFirst
+		}
+	})
 
-	case "markdown":
-		mdFormatter := formatter.NewMarkdownFormatter(!*compact, *compact, *showUnchanged)
-		output = mdFormatter.Format(summary)
+	if *regoPath != "" {
+		cfg.Rego.Paths = append(cfg.Rego.Paths, *regoPath)
+	}
 
-		// Add warnings section
-		if len(warnings) > 0 {
-			output += "\n" + formatWarningsMarkdown(warnings)
+	// Validate webhooks before spending time on the analysis
+	var hooks []*notify.Webhook
+	if *notifyHooks {
+		hooks, err = notify.NewAll(cfg.Notify)
+		if err != nil {
+			color.Red("%v", err)
+			os.Exit(1)
 		}
-
-		// Write to file or stdout
-		if *outputFile != "" {
-			err := os.WriteFile(*outputFile, []byte(output), 0644)
-			if err != nil {
-				color.Red("Error writing output file: %v", err)
+		for _, h := range hooks {
+			if !slices.Contains(infrasync.Formats(), h.Format()) {
+				color.Red("webhook %s: unknown format: %s", h.Name(), h.Format())
+				color.Yellow("Supported formats: %s", strings.Join(infrasync.Formats(), ", "))
 				os.Exit(1)
 			}
-			color.Green("✓ Output written to %s", *outputFile)
-		} else {
-			fmt.Print(output)
 		}
+	}
 
-	default:
-		color.Red("Unknown format: %s", *outputFormat)
-		color.Yellow("Supported formats: cli, markdown")
+	// Load provider versions and the previous state for version comparison
+	versions, previous, err := loadVersionBaseline(*lockFile, *previousPlan, *previousLockFile)
+	if err != nil {
+		color.Red("Error loading version information: %v", err)
 		os.Exit(1)
 	}
-}
 
-func printWarningsCLI(warnings []analyzer.Warning) {
-	if len(warnings) == 0 {
-		return
+	// Compile custom rules, load policies and plugins
+	ctx := context.Background()
+	a, err := infrasync.New(ctx, infrasync.Options{
+		Config:           cfg,
+		ProviderVersions: versions,
+		Previous:         previous,
+		SkipWarnings:     !*showWarnings,
+		Version:          version,
+		Terraform:        *terraformBin,
+		Filter:           filter,
+	})
+	if err != nil {
+		color.Red("%v", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("\n")
-	color.New(color.BgYellow, color.FgBlack, color.Bold).Printf(" 🔍 SECURITY & RISK ANALYSIS ")
-	fmt.Printf("\n\n")
+	// Parse and analyze the plans
+	report, err := analyze(ctx, a, plans)
+	if err != nil {
+		color.Red("Error analyzing plan: %v", err)
+		os.Exit(1)
+	}
 
-	criticals := filterWarnings(warnings, analyzer.RiskCritical)
-	highs := filterWarnings(warnings, analyzer.RiskHigh)
-	mediums := filterWarnings(warnings, analyzer.RiskMedium)
+	// Format and output
+	var output bytes.Buffer
+	out := io.Writer(os.Stdout)
+	if *outputFile != "" {
+		out = &output
+	}
+	opts := infrasync.FormatOptions{
+		ShowUnchanged: *showUnchanged,
+		Verbose:       *verbose,
+		Compact:       *compact,
+		HideScore:     !*showScore,
+		Color:         colorMode,
+		Order:         cfg.AttributeOrder,
+		GroupBy:       grouping,
+		MaxLength:     *maxLength,
+		Overflow:      overflow,
+		ArtifactURL:   *artifactURL,
+		Template:      tmpl,
+		Version:       version,
+	}
+	if err := report.Format(out, *outputFormat, opts); err != nil {
+		color.Red("Error writing output: %v", err)
+		os.Exit(1)
+	}
 
-	if len(criticals) > 0 {
-		color.Red("🚨 CRITICAL WARNINGS (%d):", len(criticals))
-		color.Red("─────────────────────────")
-		for _, w := range criticals {
-			color.Red("  • %s", w.Message)
-			color.HiBlack("    Resource: %s", w.Resource)
-			color.HiBlack("    %s", w.Explanation)
+	// Write to file
+	if *outputFile != "" {
+		err := os.WriteFile(*outputFile, output.Bytes(), 0644)
+		if err != nil {
+			color.Red("Error writing output file: %v", err)
+			os.Exit(1)
 		}
-		fmt.Printf("\n")
+		color.Green("✓ Output written to %s", *outputFile)
 	}
 
-	if len(highs) > 0 {
-		color.Yellow("⚠️  HIGH RISK WARNINGS (%d):", len(highs))
-		color.Yellow("──────────────────────────")
-		for _, w := range highs {
-			color.Yellow("  • %s", w.Message)
-			color.HiBlack("    Resource: %s", w.Resource)
-			color.HiBlack("    %s", w.Explanation)
-		}
-		fmt.Printf("\n")
+	// Deliver to webhooks; failures are reported but don't fail the run.
+	// Webhooks get uncolored output whatever --color says.
+	if len(hooks) > 0 {
+		notifyOpts := opts
+		notifyOpts.Color = formatter.ColorNever
+		printNotifyResults(notify.Notify(ctx, hooks, report.Notification(notifyOpts)))
 	}
 
-	if len(mediums) > 0 {
-		color.Cyan("ℹ️  MEDIUM RISK WARNINGS (%d):", len(mediums))
-		color.Cyan("────────────────────────────")
-		for _, w := range mediums {
-			color.Cyan("  • %s", w.Message)
-			color.HiBlack("    Resource: %s", w.Resource)
-		}
-		fmt.Printf("\n")
+	// Exit with appropriate code
+	if *outputFormat == "cli" || *outputFormat == "terraform" {
+		os.Exit(report.ExitCode())
 	}
 }
 
-func formatWarningsMarkdown(warnings []analyzer.Warning) string {
-	if len(warnings) == 0 {
-		return ""
+// report is the result of analyzing one or several plans
+type report interface {
+	Format(w io.Writer, format string, opts infrasync.FormatOptions) error
+	ExitCode() int
+	Notification(opts infrasync.FormatOptions) notify.Report
+}
+
+// analyze analyzes a single plan, or several plans into a combined report
+func analyze(ctx context.Context, a *infrasync.Analyzer, plans []infrasync.PlanFile) (report, error) {
+	if len(plans) == 1 {
+		r, err := a.AnalyzeFile(ctx, plans[0].Path)
+		if err != nil {
+			return nil, err
+		}
+		printPluginErrors(r.Errors)
+		return r, nil
 	}
 
-	output := "\n### 🔍 Security & Risk Analysis\n\n"
+	r, err := a.AnalyzeFiles(ctx, plans)
+	if err != nil {
+		return nil, err
+	}
+	printPluginErrors(r.Errors())
+	return r, nil
+}
 
-	criticals := filterWarnings(warnings, analyzer.RiskCritical)
-	highs := filterWarnings(warnings, analyzer.RiskHigh)
+func printPluginErrors(errs []error) {
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, color.YellowString("Plugin skipped: %v", err))
+	}
+}
 
-	if len(criticals) > 0 {
-		output += "#### 🚨 Critical Warnings\n\n"
-		for _, w := range criticals {
-			output += fmt.Sprintf("- **%s**\n", w.Message)
-			output += fmt.Sprintf("  - Resource: `%s`\n", w.Resource)
-			output += fmt.Sprintf("  - %s\n", w.Explanation)
+// printNotifyResults reports webhook deliveries on stderr, keeping stdout
+// for the report
+func printNotifyResults(results []notify.Result) {
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			fmt.Fprintf(os.Stderr, "Webhook %s skipped: not triggered\n", r.Webhook)
+		case r.Err != nil:
+			fmt.Fprintln(os.Stderr, color.YellowString("Notification failed (attempts: %d): %v", r.Attempts, r.Err))
+		default:
+			fmt.Fprintln(os.Stderr, color.GreenString("✓ Notified %s", r.Webhook))
 		}
-		output += "\n"
 	}
+}
+
+// listFlag collects the values of a repeatable, comma-separated flag
+type listFlag []string
 
-	if len(highs) > 0 {
-		output += "#### ⚠️ High Risk Warnings\n\n"
-		for _, w := range highs {
-			output += fmt.Sprintf("- **%s**\n", w.Message)
-			output += fmt.Sprintf("  - Resource: `%s`\n", w.Resource)
-			output += fmt.Sprintf("  - %s\n", w.Explanation)
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
 		}
-		output += "\n"
 	}
+	return nil
+}
 
-	return output
+// loadConfig loads the given configuration file, or the default file from
+// the working directory when none is given
+func loadConfig(filename string) (*config.Config, error) {
+	if filename == "" {
+		return config.LoadDefault()
+	}
+	return config.Load(filename)
 }
 
-func filterWarnings(warnings []analyzer.Warning, level analyzer.RiskLevel) []analyzer.Warning {
-	result := make([]analyzer.Warning, 0)
-	for _, w := range warnings {
-		if w.Level == level {
-			result = append(result, w)
+// loadVersionBaseline reads the provider versions of the lock file and
+// returns the previous plan (with its lock file applied) to compare
+// versions against, or nil when neither a previous plan nor a previous
+// lock file was given
+func loadVersionBaseline(lockFile, previousPlan, previousLockFile string) (map[string]string, *parser.PlanSummary, error) {
+	var versions map[string]string
+	if lockFile != "" {
+		var err error
+		versions, err = parser.ParseLockFile(lockFile)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
-	return result
-}
 
-func determineExitCode(summary *parser.PlanSummary, warnings []analyzer.Warning) int {
-	// Exit 0 if no changes
-	if summary.ToCreate == 0 && summary.ToUpdate == 0 && summary.ToDelete == 0 && summary.ToReplace == 0 {
-		return 0
+	if previousPlan == "" && previousLockFile == "" {
+		return versions, nil, nil
+	}
+
+	previous := &parser.PlanSummary{}
+	if previousPlan != "" {
+		var err error
+		previous, err = parser.ParsePlanFile(previousPlan)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// Exit 2 if critical warnings
-	for _, w := range warnings {
-		if w.Level == analyzer.RiskCritical {
-			return 2
+	if previousLockFile != "" {
+		previousVersions, err := parser.ParseLockFile(previousLockFile)
+		if err != nil {
+			return nil, nil, err
 		}
+		previous.ApplyLockFile(previousVersions)
 	}
 
-	// Exit 1 if there are changes
-	return 1
+	return versions, previous, nil
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/config"
+	"github.com/kvizadsaderah/infrasync/pkg/infrasync"
+	"github.com/kvizadsaderah/infrasync/pkg/policy"
+)
+
+// defaultTestManifest is used by `policy test` when no manifest is given
+const defaultTestManifest = "policy_tests.yml"
+
+func runPolicyCommand(args []string) int {
+	if len(args) < 1 || args[0] != "test" {
+		fmt.Fprintf(os.Stderr, "Usage: %s policy test [options] [manifest.yml]\n", os.Args[0])
+		return 1
+	}
+
+	flags := flag.NewFlagSet("policy test", flag.ExitOnError)
+	configFile := flags.String("config", "", "Configuration file with the rules under test (default: .infrasync.yml next to the manifest if present)")
+	regoPath := flags.String("rego", "", "Rego policy file or directory under test")
+	verbose := flags.Bool("v", false, "List passing tests as well")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s policy test [options] [manifest.yml]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Runs the analysis on fixture plans and checks the expected warnings.\n")
+		fmt.Fprintf(os.Stderr, "The manifest defaults to %s.\n\n", defaultTestManifest)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	manifestFile := defaultTestManifest
+	if flags.NArg() > 0 {
+		manifestFile = flags.Arg(0)
+	}
+
+	cfg, err := loadPolicyConfig(*configFile, manifestFile)
+	if err != nil {
+		color.Red("Error loading config: %v", err)
+		return 1
+	}
+	if *regoPath != "" {
+		cfg.Rego.Paths = append(cfg.Rego.Paths, *regoPath)
+	}
+
+	ctx := context.Background()
+	a, err := infrasync.New(ctx, infrasync.Options{Config: cfg, Version: version})
+	if err != nil {
+		color.Red("%v", err)
+		return 1
+	}
+
+	manifest, err := policy.LoadTestManifest(manifestFile)
+	if err != nil {
+		color.Red("Error loading test manifest: %v", err)
+		return 1
+	}
+
+	analyze := func(planFile string) ([]analyzer.Warning, error) {
+		report, err := a.AnalyzeFile(ctx, planFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(report.Errors) > 0 {
+			return nil, errors.Join(report.Errors...)
+		}
+		return report.AllWarnings(), nil
+	}
+
+	results := policy.RunTests(manifest, filepath.Dir(manifestFile), analyze)
+	if !policy.PrintResults(os.Stdout, manifestFile, results, *verbose) {
+		return 1
+	}
+	return 0
+}
+
+// loadPolicyConfig loads the given configuration file, or the default file
+// next to the manifest, so that tests run the same from any directory.
+// Relative Rego paths and plugin commands of that file are resolved
+// against the manifest directory, like the plan paths of the manifest.
+func loadPolicyConfig(filename, manifestFile string) (*config.Config, error) {
+	if filename != "" {
+		return config.Load(filename)
+	}
+
+	dir := filepath.Dir(manifestFile)
+	filename = filepath.Join(dir, config.DefaultFile)
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return config.Default(), nil
+	}
+	cfg, err := config.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, path := range cfg.Rego.Paths {
+		cfg.Rego.Paths[i] = resolvePath(dir, path)
+	}
+	for i, p := range cfg.Plugins {
+		// Bare command names are looked up in PATH
+		if strings.ContainsRune(p.Command, '/') {
+			cfg.Plugins[i].Command = resolvePath(dir, p.Command)
+		}
+	}
+	return cfg, nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunPolicyCommand_Example(t *testing.T) {
+	// Run from the package directory, away from the manifest and its config
+	if code := runPolicyCommand([]string{"test", "../../examples/policy/policy_tests.yml"}); code != 0 {
+		t.Errorf("policy test exited with %d, want 0", code)
+	}
+}
+
+func TestRunPolicyCommand_RelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"policy_tests.yml": `
+tests:
+  - name: t2 instances are flagged
+    plan: fixtures/plan.json
+    expect:
+      - rule: no-t2
+        resource: aws_instance.web
+`,
+		".infrasync.yml": `
+rego:
+  paths: [policies]
+`,
+		"policies/instances.rego": `package terraform
+
+warn contains result if {
+	some rc in input.resource_changes
+	rc.change.after.instance_type == "t2.micro"
+	result := {"msg": "t2 instances are deprecated", "resource": rc.address, "severity": "medium", "rule": "no-t2"}
+}
+`,
+		"fixtures/plan.json": `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_instance.web", "type": "aws_instance",
+     "change": {"actions": ["create"], "before": null, "after": {"instance_type": "t2.micro"}}}
+  ]
+}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if code := runPolicyCommand([]string{"test", filepath.Join(dir, "policy_tests.yml")}); code != 0 {
+		t.Errorf("policy test exited with %d, want 0", code)
+	}
+
+	// An explicit config is resolved against the working directory
+	if code := runPolicyCommand([]string{"test", "--config", filepath.Join(dir, "missing.yml"), filepath.Join(dir, "policy_tests.yml")}); code != 1 {
+		t.Errorf("policy test with a missing config exited with %d, want 1", code)
+	}
+}
@@ -18,15 +18,23 @@ const (
 
 // Warning represents a detected risky change
 type Warning struct {
-	Level       RiskLevel
-	Resource    string
-	Type        string
-	Message     string
-	Explanation string
+	Rule        string    `json:"rule"` // identifier of the rule that raised the warning
+	Level       RiskLevel `json:"level"`
+	Resource    string    `json:"resource"`
+	Type        string    `json:"type,omitempty"`
+	Message     string    `json:"message"`
+	Explanation string    `json:"explanation,omitempty"`
 }
 
-// AnalyzeChanges detects risky operations in the plan
-func AnalyzeChanges(summary *parser.PlanSummary) []Warning {
+// Rule is a check evaluated for every resource change next to the
+// built-in rules, such as a custom policy loaded from configuration
+type Rule interface {
+	Check(change parser.ResourceChange) []Warning
+}
+
+// AnalyzeChanges detects risky operations in the plan using the built-in
+// rules and any additional rules given
+func AnalyzeChanges(summary *parser.PlanSummary, rules ...Rule) []Warning {
 	warnings := make([]Warning, 0)
 
 	for _, change := range summary.Changes {
@@ -43,6 +51,10 @@ func AnalyzeChanges(summary *parser.PlanSummary) []Warning {
 		if change.IsUpdate {
 			warnings = append(warnings, analyzeUpdate(change)...)
 		}
+
+		for _, rule := range rules {
+			warnings = append(warnings, rule.Check(change)...)
+		}
 	}
 
 	return warnings
@@ -56,6 +68,7 @@ func analyzeDelete(change parser.ResourceChange) []Warning {
 	if strings.Contains(strings.ToLower(change.Address), "prod") ||
 		strings.Contains(strings.ToLower(change.Address), "production") {
 		warnings = append(warnings, Warning{
+			Rule:        "production-deletion",
 			Level:       RiskCritical,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -67,6 +80,7 @@ func analyzeDelete(change parser.ResourceChange) []Warning {
 	// Database deletions
 	if isDatabase(change.Type) {
 		warnings = append(warnings, Warning{
+			Rule:        "database-deletion",
 			Level:       RiskCritical,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -78,6 +92,7 @@ func analyzeDelete(change parser.ResourceChange) []Warning {
 	// Storage deletions
 	if isStorage(change.Type) {
 		warnings = append(warnings, Warning{
+			Rule:        "storage-deletion",
 			Level:       RiskHigh,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -89,6 +104,7 @@ func analyzeDelete(change parser.ResourceChange) []Warning {
 	// Network resources
 	if isNetwork(change.Type) {
 		warnings = append(warnings, Warning{
+			Rule:        "network-deletion",
 			Level:       RiskHigh,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -107,6 +123,7 @@ func analyzeReplace(change parser.ResourceChange) []Warning {
 	// Database replacements
 	if isDatabase(change.Type) {
 		warnings = append(warnings, Warning{
+			Rule:        "database-replacement",
 			Level:       RiskCritical,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -118,6 +135,7 @@ func analyzeReplace(change parser.ResourceChange) []Warning {
 	// Compute instance replacements
 	if isCompute(change.Type) {
 		warnings = append(warnings, Warning{
+			Rule:        "compute-replacement",
 			Level:       RiskHigh,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -129,6 +147,7 @@ func analyzeReplace(change parser.ResourceChange) []Warning {
 	// Load balancer replacements
 	if isLoadBalancer(change.Type) {
 		warnings = append(warnings, Warning{
+			Rule:        "load-balancer-replacement",
 			Level:       RiskHigh,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -151,6 +170,7 @@ func analyzeUpdate(change parser.ResourceChange) []Warning {
 	if isSecurityGroup(change.Type) {
 		if hasSecurityGroupWeakening(before, after) {
 			warnings = append(warnings, Warning{
+				Rule:        "security-group-relaxed",
 				Level:       RiskHigh,
 				Resource:    change.Address,
 				Type:        change.Type,
@@ -161,8 +181,9 @@ func analyzeUpdate(change parser.ResourceChange) []Warning {
 	}
 
 	// Check for encryption disabling
-	if hasEncryptionDisabled(before, after) {
+	if hasEncryptionDisabled(change) {
 		warnings = append(warnings, Warning{
+			Rule:        "encryption-disabled",
 			Level:       RiskCritical,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -172,8 +193,9 @@ func analyzeUpdate(change parser.ResourceChange) []Warning {
 	}
 
 	// Check for backup/versioning disabling
-	if hasBackupDisabled(before, after) {
+	if hasBackupDisabled(change) {
 		warnings = append(warnings, Warning{
+			Rule:        "backup-disabled",
 			Level:       RiskHigh,
 			Resource:    change.Address,
 			Type:        change.Type,
@@ -257,14 +279,13 @@ func hasSecurityGroupWeakening(before, after map[string]interface{}) bool {
 	return false
 }
 
-func hasEncryptionDisabled(before, after map[string]interface{}) bool {
+// hasEncryptionDisabled reports an encryption flag turned off. Values only
+// known after apply or left out of the plan don't count as disabled.
+func hasEncryptionDisabled(change parser.ResourceChange) bool {
 	encryptionKeys := []string{"encryption", "encrypted", "enable_encryption", "encryption_enabled"}
 
 	for _, key := range encryptionKeys {
-		beforeVal := getBool(before, key)
-		afterVal := getBool(after, key)
-
-		if beforeVal && !afterVal {
+		if wasEnabled(change, key) && isDisabled(change, key) {
 			return true
 		}
 	}
@@ -272,22 +293,17 @@ func hasEncryptionDisabled(before, after map[string]interface{}) bool {
 	return false
 }
 
-func hasBackupDisabled(before, after map[string]interface{}) bool {
+func hasBackupDisabled(change parser.ResourceChange) bool {
 	backupKeys := []string{"versioning", "backup_enabled", "enable_backup",
 		"backup_retention_days", "backup_retention_period"}
 
 	for _, key := range backupKeys {
-		beforeVal := getBool(before, key)
-		afterVal := getBool(after, key)
-
-		if beforeVal && !afterVal {
+		if wasEnabled(change, key) && isDisabled(change, key) {
 			return true
 		}
 
 		// Check for retention period reduction
-		beforeNum := getNumber(before, key)
-		afterNum := getNumber(after, key)
-		if beforeNum > 0 && afterNum == 0 {
+		if before, ok := change.BeforeValue(key).Number(); ok && before > 0 && isDisabled(change, key) {
 			return true
 		}
 	}
@@ -295,25 +311,28 @@ func hasBackupDisabled(before, after map[string]interface{}) bool {
 	return false
 }
 
-// Helper functions
-func getBool(m map[string]interface{}, key string) bool {
-	if val, ok := m[key]; ok {
-		if b, ok := val.(bool); ok {
-			return b
-		}
-	}
-	return false
+func wasEnabled(change parser.ResourceChange, key string) bool {
+	b, ok := change.BeforeValue(key).Bool()
+	return ok && b
 }
 
-func getNumber(m map[string]interface{}, key string) float64 {
-	if val, ok := m[key]; ok {
-		if n, ok := val.(float64); ok {
-			return n
-		}
+// isDisabled reports whether the planned value is known to be false, zero
+// or null
+func isDisabled(change parser.ResourceChange, key string) bool {
+	after := change.AfterValue(key)
+	if after.State == parser.ValueNull {
+		return true
 	}
-	return 0
+	if b, ok := after.Bool(); ok {
+		return !b
+	}
+	if n, ok := after.Number(); ok {
+		return n == 0
+	}
+	return false
 }
 
+// Helper functions
 func getStringSlice(m map[string]interface{}, key string) []string {
 	if val, ok := m[key]; ok {
 		if slice, ok := val.([]interface{}); ok {
@@ -170,10 +170,11 @@ func TestIsStorage(t *testing.T) {
 
 func TestHasEncryptionDisabled(t *testing.T) {
 	tests := []struct {
-		name     string
-		before   map[string]interface{}
-		after    map[string]interface{}
-		expected bool
+		name         string
+		before       map[string]interface{}
+		after        map[string]interface{}
+		afterUnknown interface{}
+		expected     bool
 	}{
 		{
 			name:     "encryption disabled",
@@ -199,11 +200,24 @@ func TestHasEncryptionDisabled(t *testing.T) {
 			after:    map[string]interface{}{"name": "test"},
 			expected: false,
 		},
+		{
+			name:         "encryption known after apply",
+			before:       map[string]interface{}{"encrypted": true},
+			after:        map[string]interface{}{},
+			afterUnknown: map[string]interface{}{"encrypted": true},
+			expected:     false,
+		},
+		{
+			name:     "encryption set to null",
+			before:   map[string]interface{}{"encrypted": true},
+			after:    map[string]interface{}{"encrypted": nil},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := hasEncryptionDisabled(tt.before, tt.after)
+			result := hasEncryptionDisabled(parser.ResourceChange{Before: tt.before, After: tt.after, AfterUnknown: tt.afterUnknown})
 			if result != tt.expected {
 				t.Errorf("hasEncryptionDisabled() = %v, want %v", result, tt.expected)
 			}
@@ -213,10 +227,11 @@ func TestHasEncryptionDisabled(t *testing.T) {
 
 func TestHasBackupDisabled(t *testing.T) {
 	tests := []struct {
-		name     string
-		before   map[string]interface{}
-		after    map[string]interface{}
-		expected bool
+		name         string
+		before       map[string]interface{}
+		after        map[string]interface{}
+		afterUnknown interface{}
+		expected     bool
 	}{
 		{
 			name:     "versioning disabled",
@@ -236,11 +251,18 @@ func TestHasBackupDisabled(t *testing.T) {
 			after:    map[string]interface{}{"backup_retention_days": float64(30)},
 			expected: false,
 		},
+		{
+			name:         "backup retention known after apply",
+			before:       map[string]interface{}{"backup_retention_days": float64(7)},
+			after:        map[string]interface{}{},
+			afterUnknown: map[string]interface{}{"backup_retention_days": true},
+			expected:     false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := hasBackupDisabled(tt.before, tt.after)
+			result := hasBackupDisabled(parser.ResourceChange{Before: tt.before, After: tt.after, AfterUnknown: tt.afterUnknown})
 			if result != tt.expected {
 				t.Errorf("hasBackupDisabled() = %v, want %v", result, tt.expected)
 			}
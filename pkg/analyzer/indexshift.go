@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// shiftSimilarity is the minimum similarity between an instance's new
+// values and its neighbour's old values to count as a shifted element
+const shiftSimilarity = 0.8
+
+// IndexShift describes a count-indexed resource whose instances change only
+// because list elements moved to a different index
+type IndexShift struct {
+	BaseAddress string
+	Type        string
+	Addresses   []string // instances whose values moved from a neighbouring index, and the trailing instances a removal deletes
+	Removed     bool     // true when elements shifted down, false when shifted up
+}
+
+// Warning converts the index shift into a warning listing every affected instance
+func (s IndexShift) Warning() Warning {
+	cause := "Inserting an element into"
+	if s.Removed {
+		cause = "Removing an element from"
+	}
+	return Warning{
+		Rule:     "count-index-shift",
+		Level:    RiskHigh,
+		Resource: s.BaseAddress,
+		Type:     s.Type,
+		Message:  fmt.Sprintf("%s the count list affects %d instance(s) of %s", cause, len(s.Addresses), s.BaseAddress),
+		Explanation: fmt.Sprintf("Affected: %s. Convert to for_each keyed by a stable value so that changing "+
+			"one element only touches that element", strings.Join(s.Addresses, ", ")),
+	}
+}
+
+type indexedChange struct {
+	index  int
+	change parser.ResourceChange
+}
+
+// DetectIndexShifts finds count-indexed resources where an element was
+// removed from or inserted into the middle of the list, so that the values
+// of every later index move to a neighbouring index and those instances
+// are updated or replaced
+func DetectIndexShifts(summary *parser.PlanSummary) []IndexShift {
+	groups := make(map[string][]indexedChange)
+	for _, change := range summary.Changes {
+		base, index, ok := splitCountIndex(change.Address)
+		if !ok {
+			continue
+		}
+		groups[base] = append(groups[base], indexedChange{index: index, change: change})
+	}
+
+	bases := make([]string, 0, len(groups))
+	for base := range groups {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+
+	shifts := make([]IndexShift, 0)
+	for _, base := range bases {
+		instances := groups[base]
+		if len(instances) < 2 {
+			continue
+		}
+		sort.Slice(instances, func(i, j int) bool { return instances[i].index < instances[j].index })
+
+		byIndex := make(map[int]parser.ResourceChange, len(instances))
+		for _, ic := range instances {
+			byIndex[ic.index] = ic.change
+		}
+
+		if shift, ok := findShift(base, instances, byIndex, 1); ok {
+			shifts = append(shifts, shift)
+		} else if shift, ok := findShift(base, instances, byIndex, -1); ok {
+			shifts = append(shifts, shift)
+		}
+	}
+
+	return shifts
+}
+
+// findShift looks for instances whose new values equal the old values of
+// the instance at index+offset. An offset of 1 means elements moved down
+// after a removal, -1 means they moved up after an insertion.
+func findShift(base string, instances []indexedChange, byIndex map[int]parser.ResourceChange, offset int) (IndexShift, bool) {
+	shift := IndexShift{BaseAddress: base, Removed: offset > 0}
+	last := -1
+
+	for _, ic := range instances {
+		c := ic.change
+		if !c.IsUpdate && !c.IsReplace {
+			continue
+		}
+		neighbour, ok := byIndex[ic.index+offset]
+		if !ok {
+			continue
+		}
+		// Instances of one list differ in a few attributes only, so an
+		// updated instance stays similar to its own old values; the new
+		// values must match the neighbour's better than its own
+		unknown := toMap(c.AfterUnknown)
+		similarity := attributeSimilarity(neighbour.Before, c.After, unknown)
+		if similarity >= shiftSimilarity && similarity > attributeSimilarity(c.Before, c.After, unknown) {
+			shift.Type = c.Type
+			shift.Addresses = append(shift.Addresses, c.Address)
+			last = ic.index
+		}
+	}
+	if len(shift.Addresses) == 0 {
+		return shift, false
+	}
+
+	// After a removal the last instances are deleted, as their values
+	// moved down
+	if shift.Removed {
+		for _, ic := range instances {
+			if ic.index > last && ic.change.IsDelete {
+				shift.Addresses = append(shift.Addresses, ic.change.Address)
+			}
+		}
+	}
+	return shift, true
+}
+
+// splitCountIndex splits "aws_subnet.private[2]" into "aws_subnet.private" and 2.
+// Addresses without a trailing numeric index, such as for_each keys, are rejected.
+func splitCountIndex(address string) (string, int, bool) {
+	if !strings.HasSuffix(address, "]") {
+		return "", 0, false
+	}
+	open := strings.LastIndex(address, "[")
+	if open < 0 {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(address[open+1 : len(address)-1])
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+	return address[:open], index, true
+}
@@ -0,0 +1,192 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func subnet(index, beforeCidr, afterCidr string, set func(*parser.ResourceChange)) parser.ResourceChange {
+	c := parser.ResourceChange{
+		Address: "aws_subnet.private[" + index + "]",
+		Type:    "aws_subnet",
+		Before:  map[string]interface{}{"cidr_block": beforeCidr, "vpc_id": "vpc-1"},
+		After:   map[string]interface{}{"cidr_block": afterCidr, "vpc_id": "vpc-1"},
+	}
+	set(&c)
+	return c
+}
+
+// fullSubnet is an aws_subnet with the attributes of a real plan, of which
+// only the CIDR block and availability zone differ between list elements
+func fullSubnet(index int, az string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                              fmt.Sprintf("subnet-%d", index),
+		"arn":                             fmt.Sprintf("arn:aws:ec2:eu-west-1:123456789012:subnet/subnet-%d", index),
+		"cidr_block":                      fmt.Sprintf("10.0.%d.0/24", index),
+		"availability_zone":               "eu-west-1" + az,
+		"vpc_id":                          "vpc-1",
+		"owner_id":                        "123456789012",
+		"map_public_ip_on_launch":         false,
+		"assign_ipv6_address_on_creation": false,
+		"enable_dns64":                    false,
+		"enable_resource_name_dns_a_record_on_launch": false,
+		"ipv6_native":                         false,
+		"private_dns_hostname_type_on_launch": "ip-name",
+		"tags":                                map[string]interface{}{"Tier": "private"},
+		"tags_all":                            map[string]interface{}{"Tier": "private", "Team": "platform"},
+	}
+}
+
+func TestDetectIndexShifts_FullAttributes(t *testing.T) {
+	azs := []string{"a", "b", "c", "d"}
+	removed := func(i int) parser.ResourceChange {
+		// "b" was removed: every later subnet takes the values of the next one
+		c := parser.ResourceChange{
+			Address: fmt.Sprintf("aws_subnet.private[%d]", i),
+			Type:    "aws_subnet",
+			Before:  fullSubnet(i, azs[i]),
+		}
+		switch {
+		case i == 0:
+			c.IsNoOp = true
+			c.After = c.Before
+		case i < len(azs)-1:
+			c.IsReplace = true
+			c.After = fullSubnet(i+1, azs[i+1])
+			delete(c.After, "id")
+			delete(c.After, "arn")
+			c.AfterUnknown = map[string]interface{}{"id": true, "arn": true}
+		default:
+			c.IsDelete = true
+		}
+		return c
+	}
+	tagged := func(i int) parser.ResourceChange {
+		// An unrelated tag update on every subnet
+		c := parser.ResourceChange{
+			Address:  fmt.Sprintf("aws_subnet.private[%d]", i),
+			Type:     "aws_subnet",
+			IsUpdate: true,
+			Before:   fullSubnet(i, azs[i]),
+			After:    fullSubnet(i, azs[i]),
+		}
+		c.After["tags"] = map[string]interface{}{"Tier": "internal"}
+		return c
+	}
+
+	summary := &parser.PlanSummary{}
+	for i := range azs {
+		summary.Changes = append(summary.Changes, removed(i))
+	}
+	shifts := DetectIndexShifts(summary)
+	if len(shifts) != 1 || !shifts[0].Removed {
+		t.Fatalf("Expected 1 removal shift, got %+v", shifts)
+	}
+	if want := []string{"aws_subnet.private[1]", "aws_subnet.private[2]", "aws_subnet.private[3]"}; !reflect.DeepEqual(shifts[0].Addresses, want) {
+		t.Errorf("Addresses = %v, want %v", shifts[0].Addresses, want)
+	}
+
+	summary = &parser.PlanSummary{}
+	for i := range azs {
+		summary.Changes = append(summary.Changes, tagged(i))
+	}
+	if shifts := DetectIndexShifts(summary); len(shifts) != 0 {
+		t.Errorf("Expected no shifts for independent updates, got %+v", shifts)
+	}
+}
+
+func TestDetectIndexShifts_RemovalFromMiddle(t *testing.T) {
+	replace := func(c *parser.ResourceChange) { c.IsReplace = true }
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			subnet("0", "10.0.0.0/24", "10.0.0.0/24", func(c *parser.ResourceChange) { c.IsNoOp = true }),
+			subnet("1", "10.0.1.0/24", "10.0.2.0/24", replace),
+			subnet("2", "10.0.2.0/24", "10.0.3.0/24", replace),
+			subnet("3", "10.0.3.0/24", "", func(c *parser.ResourceChange) {
+				c.IsDelete = true
+				c.After = nil
+			}),
+		},
+	}
+
+	shifts := DetectIndexShifts(summary)
+
+	if len(shifts) != 1 {
+		t.Fatalf("Expected 1 shift, got %d: %+v", len(shifts), shifts)
+	}
+	s := shifts[0]
+	if s.BaseAddress != "aws_subnet.private" || !s.Removed {
+		t.Errorf("Unexpected shift: %+v", s)
+	}
+	// The deleted last instance is affected too
+	if want := []string{"aws_subnet.private[1]", "aws_subnet.private[2]", "aws_subnet.private[3]"}; !reflect.DeepEqual(s.Addresses, want) {
+		t.Errorf("Unexpected affected addresses: %v", s.Addresses)
+	}
+	if w := s.Warning(); !strings.Contains(w.Message, "affects 3 instance(s)") || !strings.Contains(w.Explanation, "aws_subnet.private[3]") {
+		t.Errorf("Unexpected warning: %+v", w)
+	}
+}
+
+func TestDetectIndexShifts_InsertionShiftsUp(t *testing.T) {
+	update := func(c *parser.ResourceChange) { c.IsUpdate = true }
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			subnet("0", "10.0.0.0/24", "10.0.9.0/24", update),
+			subnet("1", "10.0.1.0/24", "10.0.0.0/24", update),
+			subnet("2", "", "10.0.1.0/24", func(c *parser.ResourceChange) {
+				c.IsCreate = true
+				c.Before = nil
+			}),
+		},
+	}
+
+	shifts := DetectIndexShifts(summary)
+
+	if len(shifts) != 1 || shifts[0].Removed {
+		t.Fatalf("Expected 1 insertion shift, got %+v", shifts)
+	}
+	if len(shifts[0].Addresses) != 1 || shifts[0].Addresses[0] != "aws_subnet.private[1]" {
+		t.Errorf("Unexpected affected addresses: %v", shifts[0].Addresses)
+	}
+}
+
+func TestDetectIndexShifts_IndependentUpdates(t *testing.T) {
+	update := func(c *parser.ResourceChange) { c.IsUpdate = true }
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			subnet("0", "10.0.0.0/24", "10.1.0.0/24", update),
+			subnet("1", "10.0.1.0/24", "10.1.1.0/24", update),
+		},
+	}
+
+	if shifts := DetectIndexShifts(summary); len(shifts) != 0 {
+		t.Errorf("Expected no shifts, got %+v", shifts)
+	}
+}
+
+func TestSplitCountIndex(t *testing.T) {
+	tests := []struct {
+		address string
+		base    string
+		index   int
+		ok      bool
+	}{
+		{"aws_instance.web[3]", "aws_instance.web", 3, true},
+		{"module.app[0].aws_instance.web[12]", "module.app[0].aws_instance.web", 12, true},
+		{`aws_instance.web["a"]`, "", 0, false},
+		{"aws_instance.web", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			base, index, ok := splitCountIndex(tt.address)
+			if base != tt.base || index != tt.index || ok != tt.ok {
+				t.Errorf("splitCountIndex(%s) = %q, %d, %v", tt.address, base, index, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// MassChangeThresholds limits how much of a scope may be deleted or replaced.
+// A zero value disables the corresponding check.
+type MassChangeThresholds struct {
+	DeleteRatio  float64 `yaml:"delete_ratio"`  // fraction of existing resources being destroyed
+	ReplaceRatio float64 `yaml:"replace_ratio"` // fraction of existing resources being replaced
+	Deletes      int     `yaml:"deletes"`       // absolute number of destroyed resources
+	Replaces     int     `yaml:"replaces"`      // absolute number of replaced resources
+}
+
+// MassChangeConfig configures plan-level anomaly detection
+type MassChangeConfig struct {
+	Plan      MassChangeThresholds `yaml:"plan"`
+	PerType   MassChangeThresholds `yaml:"per_type"`
+	PerModule MassChangeThresholds `yaml:"per_module"`
+
+	// MinGroupSize is the number of existing resources a scope
+	// needs before its ratios are checked, so that replacing the only
+	// instance of something is not reported as a mass change.
+	MinGroupSize int `yaml:"min_group_size"`
+}
+
+// DefaultMassChangeConfig returns the thresholds used when no configuration is given
+func DefaultMassChangeConfig() MassChangeConfig {
+	return MassChangeConfig{
+		Plan: MassChangeThresholds{
+			DeleteRatio:  0.3,
+			ReplaceRatio: 0.3,
+			Deletes:      25,
+			Replaces:     25,
+		},
+		PerType: MassChangeThresholds{
+			DeleteRatio:  0.5,
+			ReplaceRatio: 0.8,
+		},
+		PerModule: MassChangeThresholds{
+			DeleteRatio:  0.5,
+			ReplaceRatio: 0.8,
+		},
+		MinGroupSize: 3,
+	}
+}
+
+// changeGroup counts the changes of one scope (whole plan, type or module)
+type changeGroup struct {
+	existing int
+	deletes  int
+	replaces int
+}
+
+func (g *changeGroup) add(change parser.ResourceChange) {
+	// Resources being created are not part of the current state
+	if change.IsCreate {
+		return
+	}
+	g.existing++
+	if change.IsDelete {
+		g.deletes++
+	}
+	if change.IsReplace {
+		g.replaces++
+	}
+}
+
+// AnalyzePlan detects plan-wide anomalies such as destroying a large share
+// of the state or replacing every instance in a module. These almost always
+// point at a wrong workspace, var file or an unexpected provider upgrade.
+func AnalyzePlan(summary *parser.PlanSummary, cfg MassChangeConfig) []Warning {
+	warnings := make([]Warning, 0)
+
+	plan := &changeGroup{}
+	byType := make(map[string]*changeGroup)
+	byModule := make(map[string]*changeGroup)
+
+	for _, change := range summary.Changes {
+		plan.add(change)
+
+		if byType[change.Type] == nil {
+			byType[change.Type] = &changeGroup{}
+		}
+		byType[change.Type].add(change)
+
+		if module := change.Module(); module != "" {
+			if byModule[module] == nil {
+				byModule[module] = &changeGroup{}
+			}
+			byModule[module].add(change)
+		}
+	}
+
+	warnings = append(warnings, checkMassChange("plan", "", "of existing resources", plan, cfg.Plan, cfg.MinGroupSize)...)
+
+	for _, t := range sortedKeys(byType) {
+		what := fmt.Sprintf("of %s resources", t)
+		warnings = append(warnings, checkMassChange(t, t, what, byType[t], cfg.PerType, cfg.MinGroupSize)...)
+	}
+
+	for _, m := range sortedKeys(byModule) {
+		what := fmt.Sprintf("of resources in %s", m)
+		warnings = append(warnings, checkMassChange(m, "", what, byModule[m], cfg.PerModule, cfg.MinGroupSize)...)
+	}
+
+	return warnings
+}
+
+func checkMassChange(scope, resourceType, what string, g *changeGroup, t MassChangeThresholds, minSize int) []Warning {
+	warnings := make([]Warning, 0)
+
+	if g.existing == 0 || g.existing < minSize {
+		return warnings
+	}
+
+	if exceeds(g.deletes, g.existing, t.DeleteRatio, t.Deletes) {
+		warnings = append(warnings, Warning{
+			Rule:        "mass-deletion",
+			Level:       RiskCritical,
+			Resource:    scope,
+			Type:        resourceType,
+			Message:     fmt.Sprintf("%s %s will be DESTROYED (%d of %d)", percent(g.deletes, g.existing), what, g.deletes, g.existing),
+			Explanation: "Mass deletions usually mean a wrong workspace, var file or backend configuration",
+		})
+	}
+
+	if exceeds(g.replaces, g.existing, t.ReplaceRatio, t.Replaces) {
+		warnings = append(warnings, Warning{
+			Rule:        "mass-replacement",
+			Level:       RiskCritical,
+			Resource:    scope,
+			Type:        resourceType,
+			Message:     fmt.Sprintf("%s %s will be REPLACED (%d of %d)", percent(g.replaces, g.existing), what, g.replaces, g.existing),
+			Explanation: "Mass replacements usually mean a provider upgrade or a changed input forcing recreation",
+		})
+	}
+
+	return warnings
+}
+
+func exceeds(count, total int, maxRatio float64, maxCount int) bool {
+	if count == 0 {
+		return false
+	}
+	if maxCount > 0 && count >= maxCount {
+		return true
+	}
+	return maxRatio > 0 && float64(count)/float64(total) >= maxRatio
+}
+
+func percent(count, total int) string {
+	return fmt.Sprintf("%.0f%%", float64(count)*100/float64(total))
+}
+
+func sortedKeys(m map[string]*changeGroup) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func makeChanges(prefix, resourceType string, n int, set func(*parser.ResourceChange)) []parser.ResourceChange {
+	changes := make([]parser.ResourceChange, 0, n)
+	for i := 0; i < n; i++ {
+		c := parser.ResourceChange{
+			Address: fmt.Sprintf("%s%s.r%d", prefix, resourceType, i),
+			Type:    resourceType,
+			IsNoOp:  true,
+		}
+		if set != nil {
+			c.IsNoOp = false
+			set(&c)
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+func TestAnalyzePlan_MassDeletion(t *testing.T) {
+	changes := makeChanges("", "aws_instance", 6, nil)
+	changes = append(changes, makeChanges("", "aws_s3_bucket", 4, func(c *parser.ResourceChange) { c.IsDelete = true })...)
+
+	warnings := AnalyzePlan(&parser.PlanSummary{Changes: changes}, DefaultMassChangeConfig())
+
+	found := false
+	for _, w := range warnings {
+		if w.Resource == "plan" && w.Level == RiskCritical {
+			found = true
+			if w.Message != "40% of existing resources will be DESTROYED (4 of 10)" {
+				t.Errorf("Unexpected message: %s", w.Message)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected plan-level warning for deleting 40% of resources")
+	}
+}
+
+func TestAnalyzePlan_CreatesDoNotCountAsExisting(t *testing.T) {
+	changes := makeChanges("", "aws_instance", 2, nil)
+	changes = append(changes, makeChanges("", "aws_iam_role", 20, func(c *parser.ResourceChange) { c.IsCreate = true })...)
+	changes = append(changes, makeChanges("", "aws_s3_bucket", 1, func(c *parser.ResourceChange) { c.IsDelete = true })...)
+
+	warnings := AnalyzePlan(&parser.PlanSummary{Changes: changes}, DefaultMassChangeConfig())
+
+	if len(warnings) != 1 || warnings[0].Message != "33% of existing resources will be DESTROYED (1 of 3)" {
+		t.Errorf("Expected single plan-level warning based on existing resources, got %+v", warnings)
+	}
+}
+
+func TestAnalyzePlan_AbsoluteThreshold(t *testing.T) {
+	changes := makeChanges("", "aws_instance", 100, nil)
+	changes = append(changes, makeChanges("", "aws_route", 5, func(c *parser.ResourceChange) { c.IsReplace = true })...)
+
+	cfg := MassChangeConfig{Plan: MassChangeThresholds{Replaces: 5}}
+	warnings := AnalyzePlan(&parser.PlanSummary{Changes: changes}, cfg)
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d", len(warnings))
+	}
+	if warnings[0].Resource != "plan" {
+		t.Errorf("Expected plan scope, got %s", warnings[0].Resource)
+	}
+}
+
+func TestAnalyzePlan_ModuleFullyReplaced(t *testing.T) {
+	changes := makeChanges("", "aws_instance", 50, nil)
+	changes = append(changes, makeChanges("module.workers.", "aws_instance", 3, func(c *parser.ResourceChange) { c.IsReplace = true })...)
+
+	cfg := DefaultMassChangeConfig()
+	cfg.PerType = MassChangeThresholds{}
+	warnings := AnalyzePlan(&parser.PlanSummary{Changes: changes}, cfg)
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %+v", len(warnings), warnings)
+	}
+	if warnings[0].Resource != "module.workers" {
+		t.Errorf("Expected module scope, got %s", warnings[0].Resource)
+	}
+}
+
+func TestAnalyzePlan_MinGroupSize(t *testing.T) {
+	changes := makeChanges("", "aws_instance", 50, nil)
+	changes = append(changes, makeChanges("", "aws_eip", 1, func(c *parser.ResourceChange) { c.IsReplace = true })...)
+
+	warnings := AnalyzePlan(&parser.PlanSummary{Changes: changes}, DefaultMassChangeConfig())
+
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings for replacing a single resource, got %+v", warnings)
+	}
+}
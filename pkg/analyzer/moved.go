@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// DefaultMoveSimilarity is the minimum attribute similarity for a
+// delete/create pair to be reported as a likely move
+const DefaultMoveSimilarity = 0.8
+
+// MoveSuggestion pairs a destroyed resource with a created resource of the
+// same type that looks like the same object under a new address
+type MoveSuggestion struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Type       string  `json:"type"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Block returns a ready-to-paste moved block for the suggestion
+func (m MoveSuggestion) Block() string {
+	return fmt.Sprintf("moved {\n  from = %s\n  to   = %s\n}\n", m.From, m.To)
+}
+
+// Warning converts the suggestion into a warning. Moves of stateful
+// resources are high risk because the object is destroyed, not renamed.
+func (m MoveSuggestion) Warning() Warning {
+	level := RiskMedium
+	if isDatabase(m.Type) || isStorage(m.Type) {
+		level = RiskHigh
+	}
+	return Warning{
+		Rule:     "missing-moved-block",
+		Level:    level,
+		Resource: m.From,
+		Type:     m.Type,
+		Message:  fmt.Sprintf("Resource looks moved to %s without a moved block", m.To),
+		Explanation: fmt.Sprintf("%.0f%% of attributes match; Terraform will destroy and recreate it instead of "+
+			"renaming it in state. Add a moved block to keep the existing object", m.Similarity*100),
+	}
+}
+
+// identityKeys are assigned by the provider and always differ between two
+// objects, so they carry no signal about whether a resource was moved
+var identityKeys = map[string]bool{
+	"id":        true,
+	"arn":       true,
+	"self_link": true,
+	"unique_id": true,
+}
+
+// DetectMoves pairs deleted resources with created resources of the same
+// type and returns the pairs whose attributes are at least minSimilarity
+// alike. Each resource is used in at most one suggestion, best matches first.
+func DetectMoves(summary *parser.PlanSummary, minSimilarity float64) []MoveSuggestion {
+	deletes := make([]parser.ResourceChange, 0)
+	creates := make([]parser.ResourceChange, 0)
+	for _, change := range summary.Changes {
+		if change.IsDelete {
+			deletes = append(deletes, change)
+		}
+		if change.IsCreate {
+			creates = append(creates, change)
+		}
+	}
+
+	candidates := make([]MoveSuggestion, 0)
+	for _, d := range deletes {
+		for _, c := range creates {
+			if d.Type != c.Type {
+				continue
+			}
+			score := attributeSimilarity(d.Before, c.After, toMap(c.AfterUnknown))
+			if score >= minSimilarity {
+				candidates = append(candidates, MoveSuggestion{
+					From:       d.Address,
+					To:         c.Address,
+					Type:       d.Type,
+					Similarity: score,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Similarity > candidates[j].Similarity
+	})
+
+	used := make(map[string]bool)
+	moves := make([]MoveSuggestion, 0)
+	for _, m := range candidates {
+		if used[m.From] || used[m.To] {
+			continue
+		}
+		used[m.From] = true
+		used[m.To] = true
+		moves = append(moves, m)
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].From < moves[j].From })
+	return moves
+}
+
+// attributeSimilarity returns the fraction of comparable attributes that
+// are equal before and after. Attributes unknown until apply, provider
+// assigned identifiers and attributes empty on both sides are ignored.
+func attributeSimilarity(before, after, afterUnknown map[string]interface{}) float64 {
+	keys := make(map[string]bool)
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	compared := 0
+	matching := 0
+	for key := range keys {
+		if identityKeys[key] {
+			continue
+		}
+		if unknown, ok := afterUnknown[key].(bool); ok && unknown {
+			continue
+		}
+		beforeVal := before[key]
+		afterVal := after[key]
+		if isEmptyValue(beforeVal) && isEmptyValue(afterVal) {
+			continue
+		}
+
+		compared++
+		if reflect.DeepEqual(beforeVal, afterVal) {
+			matching++
+		}
+	}
+
+	if compared == 0 {
+		return 0
+	}
+	return float64(matching) / float64(compared)
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+func toMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	return make(map[string]interface{})
+}
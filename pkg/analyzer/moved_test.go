@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestDetectMoves_ModuleRefactor(t *testing.T) {
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			{
+				Address:  "aws_s3_bucket.logs",
+				Type:     "aws_s3_bucket",
+				IsDelete: true,
+				Before: map[string]interface{}{
+					"id":     "acme-logs",
+					"arn":    "arn:aws:s3:::acme-logs",
+					"bucket": "acme-logs",
+					"tags":   map[string]interface{}{"team": "platform"},
+				},
+			},
+			{
+				Address:  "module.storage.aws_s3_bucket.logs",
+				Type:     "aws_s3_bucket",
+				IsCreate: true,
+				After: map[string]interface{}{
+					"bucket": "acme-logs",
+					"tags":   map[string]interface{}{"team": "platform"},
+				},
+				AfterUnknown: map[string]interface{}{"id": true, "arn": true},
+			},
+			{
+				Address:  "module.storage.aws_s3_bucket.assets",
+				Type:     "aws_s3_bucket",
+				IsCreate: true,
+				After: map[string]interface{}{
+					"bucket": "acme-assets",
+					"tags":   map[string]interface{}{"team": "web"},
+				},
+			},
+		},
+	}
+
+	moves := DetectMoves(summary, DefaultMoveSimilarity)
+
+	if len(moves) != 1 {
+		t.Fatalf("Expected 1 move, got %d: %+v", len(moves), moves)
+	}
+	if moves[0].From != "aws_s3_bucket.logs" || moves[0].To != "module.storage.aws_s3_bucket.logs" {
+		t.Errorf("Unexpected move: %+v", moves[0])
+	}
+	if moves[0].Similarity != 1 {
+		t.Errorf("Expected similarity 1, got %f", moves[0].Similarity)
+	}
+
+	expected := "moved {\n  from = aws_s3_bucket.logs\n  to   = module.storage.aws_s3_bucket.logs\n}\n"
+	if moves[0].Block() != expected {
+		t.Errorf("Unexpected block:\n%s", moves[0].Block())
+	}
+
+	if w := moves[0].Warning(); w.Level != RiskHigh {
+		t.Errorf("Expected high risk for moved storage, got %s", w.Level)
+	}
+}
+
+func TestDetectMoves_DifferentTypesNotPaired(t *testing.T) {
+	attrs := map[string]interface{}{"name": "web"}
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			{Address: "aws_instance.web", Type: "aws_instance", IsDelete: true, Before: attrs},
+			{Address: "aws_eip.web", Type: "aws_eip", IsCreate: true, After: attrs},
+		},
+	}
+
+	if moves := DetectMoves(summary, DefaultMoveSimilarity); len(moves) != 0 {
+		t.Errorf("Expected no moves across types, got %+v", moves)
+	}
+}
+
+func TestDetectMoves_EachResourceUsedOnce(t *testing.T) {
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			{Address: "aws_sqs_queue.a", Type: "aws_sqs_queue", IsDelete: true,
+				Before: map[string]interface{}{"name": "jobs", "delay_seconds": float64(0), "fifo_queue": false}},
+			{Address: "aws_sqs_queue.b", Type: "aws_sqs_queue", IsDelete: true,
+				Before: map[string]interface{}{"name": "jobs", "delay_seconds": float64(0), "fifo_queue": true}},
+			{Address: "aws_sqs_queue.new", Type: "aws_sqs_queue", IsCreate: true,
+				After: map[string]interface{}{"name": "jobs", "delay_seconds": float64(0), "fifo_queue": false}},
+		},
+	}
+
+	moves := DetectMoves(summary, 0.5)
+
+	if len(moves) != 1 {
+		t.Fatalf("Expected 1 move, got %d: %+v", len(moves), moves)
+	}
+	if moves[0].From != "aws_sqs_queue.a" {
+		t.Errorf("Expected best match aws_sqs_queue.a, got %s", moves[0].From)
+	}
+}
+
+func TestAttributeSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   map[string]interface{}
+		after    map[string]interface{}
+		unknown  map[string]interface{}
+		expected float64
+	}{
+		{
+			name:     "identical",
+			before:   map[string]interface{}{"name": "a", "size": float64(10)},
+			after:    map[string]interface{}{"name": "a", "size": float64(10)},
+			expected: 1,
+		},
+		{
+			name:     "half different",
+			before:   map[string]interface{}{"name": "a", "size": float64(10)},
+			after:    map[string]interface{}{"name": "b", "size": float64(10)},
+			expected: 0.5,
+		},
+		{
+			name:     "unknown and empty attributes ignored",
+			before:   map[string]interface{}{"name": "a", "endpoint": "x.example.com", "description": ""},
+			after:    map[string]interface{}{"name": "a", "description": nil},
+			unknown:  map[string]interface{}{"endpoint": true},
+			expected: 1,
+		},
+		{
+			name:     "nothing comparable",
+			before:   map[string]interface{}{"id": "i-123"},
+			after:    map[string]interface{}{},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := attributeSimilarity(tt.before, tt.after, tt.unknown)
+			if result != tt.expected {
+				t.Errorf("attributeSimilarity() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
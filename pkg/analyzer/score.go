@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// ScoreWeights configures how the numeric risk score is computed. A
+// resource scores its action weight times its category and environment
+// multipliers, plus the weight of every warning raised for it.
+type ScoreWeights struct {
+	Actions      map[string]float64    `yaml:"actions"`      // create, update, replace, delete
+	Categories   map[string]float64    `yaml:"categories"`   // database, storage, network, ...
+	Environments map[string]float64    `yaml:"environments"` // address substring -> multiplier
+	Environment  string                `yaml:"environment"`  // applies one environment to every resource
+	Warnings     map[RiskLevel]float64 `yaml:"warnings"`
+	Thresholds   ScoreThresholds       `yaml:"thresholds"`
+}
+
+// ScoreThresholds maps a numeric plan score to a risk level
+type ScoreThresholds struct {
+	Medium   float64 `yaml:"medium"`
+	High     float64 `yaml:"high"`
+	Critical float64 `yaml:"critical"`
+}
+
+// DefaultScoreWeights returns the weights used when no configuration is given
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		Actions: map[string]float64{
+			"create":  1,
+			"update":  2,
+			"replace": 8,
+			"delete":  10,
+		},
+		Categories: map[string]float64{
+			"database":       3,
+			"storage":        2,
+			"network":        2,
+			"security_group": 2,
+			"load_balancer":  1.5,
+			"compute":        1.5,
+		},
+		Environments: map[string]float64{
+			"prod":    2,
+			"staging": 1.2,
+		},
+		Warnings: map[RiskLevel]float64{
+			RiskCritical: 25,
+			RiskHigh:     10,
+			RiskMedium:   3,
+			RiskLow:      1,
+		},
+		Thresholds: ScoreThresholds{
+			Medium:   20,
+			High:     60,
+			Critical: 120,
+		},
+	}
+}
+
+// ResourceScore is the risk score of a single resource change
+type ResourceScore struct {
+	Address string  `json:"address"`
+	Module  string  `json:"module"`
+	Score   float64 `json:"score"`
+}
+
+// PlanScore aggregates risk scores per resource, per module and for the plan
+type PlanScore struct {
+	Total     float64            `json:"total"`
+	Level     RiskLevel          `json:"level"`
+	ByModule  map[string]float64 `json:"by_module"` // root module resources are under ""
+	Resources []ResourceScore    `json:"resources"` // highest score first
+}
+
+// ScorePlan computes the numeric risk score of the plan. Warnings that do
+// not belong to a resource, such as mass-change warnings, only add to the
+// plan total.
+func ScorePlan(summary *parser.PlanSummary, warnings []Warning, weights ScoreWeights) *PlanScore {
+	warningScores := make(map[string]float64)
+	for _, w := range warnings {
+		warningScores[w.Resource] += weights.Warnings[w.Level]
+	}
+
+	score := &PlanScore{ByModule: make(map[string]float64)}
+	scored := make(map[string]bool)
+
+	for _, change := range summary.Changes {
+		action := change.Action()
+		if action == "" || action == "read" || action == "no-op" {
+			continue
+		}
+
+		s := weights.Actions[action] * categoryWeight(change.Type, weights) * environmentWeight(change.Address, weights)
+		s += warningScores[change.Address]
+		scored[change.Address] = true
+
+		module := change.Module()
+		score.Resources = append(score.Resources, ResourceScore{Address: change.Address, Module: module, Score: s})
+		score.ByModule[module] += s
+		score.Total += s
+	}
+
+	for resource, s := range warningScores {
+		if !scored[resource] {
+			score.Total += s
+		}
+	}
+
+	sort.SliceStable(score.Resources, func(i, j int) bool { return score.Resources[i].Score > score.Resources[j].Score })
+	score.Level = weights.Thresholds.level(score.Total)
+
+	return score
+}
+
+func (t ScoreThresholds) level(total float64) RiskLevel {
+	switch {
+	case t.Critical > 0 && total >= t.Critical:
+		return RiskCritical
+	case t.High > 0 && total >= t.High:
+		return RiskHigh
+	case t.Medium > 0 && total >= t.Medium:
+		return RiskMedium
+	default:
+		return RiskLow
+	}
+}
+
+// categoryWeight returns the multiplier of the first matching category
+func categoryWeight(resourceType string, weights ScoreWeights) float64 {
+	categories := []struct {
+		name    string
+		matches func(string) bool
+	}{
+		{"database", isDatabase},
+		{"storage", isStorage},
+		{"network", isNetwork},
+		{"security_group", isSecurityGroup},
+		{"load_balancer", isLoadBalancer},
+		{"compute", isCompute},
+	}
+
+	for _, c := range categories {
+		if c.matches(resourceType) {
+			if w, ok := weights.Categories[c.name]; ok {
+				return w
+			}
+			break
+		}
+	}
+	if w, ok := weights.Categories["other"]; ok {
+		return w
+	}
+	return 1
+}
+
+// environmentWeight returns the highest multiplier whose key appears in
+// the address, or the configured environment's multiplier when one is set
+func environmentWeight(address string, weights ScoreWeights) float64 {
+	if weights.Environment != "" {
+		if w, ok := weights.Environments[weights.Environment]; ok {
+			return w
+		}
+		return 1
+	}
+
+	result := 1.0
+	lower := strings.ToLower(address)
+	for env, w := range weights.Environments {
+		if strings.Contains(lower, strings.ToLower(env)) && w > result {
+			result = w
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestScorePlan(t *testing.T) {
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			{Address: "aws_iam_role.app", Type: "aws_iam_role", IsCreate: true},
+			{Address: "module.db.aws_db_instance.prod_main", Type: "aws_db_instance", IsDelete: true},
+			{Address: "aws_instance.web", Type: "aws_instance", IsNoOp: true},
+		},
+	}
+	warnings := []Warning{
+		{Level: RiskCritical, Resource: "module.db.aws_db_instance.prod_main"},
+		{Level: RiskCritical, Resource: "plan"},
+	}
+
+	score := ScorePlan(summary, warnings, DefaultScoreWeights())
+
+	// delete (10) x database (3) x prod (2) + critical warning (25)
+	dbScore := 10.0*3*2 + 25
+	if len(score.Resources) != 2 {
+		t.Fatalf("Expected 2 scored resources, got %d", len(score.Resources))
+	}
+	if score.Resources[0].Address != "module.db.aws_db_instance.prod_main" || score.Resources[0].Score != dbScore {
+		t.Errorf("Unexpected top resource: %+v", score.Resources[0])
+	}
+	if score.ByModule["module.db"] != dbScore {
+		t.Errorf("Expected module.db score %v, got %v", dbScore, score.ByModule["module.db"])
+	}
+	if score.ByModule[""] != 1 {
+		t.Errorf("Expected root module score 1, got %v", score.ByModule[""])
+	}
+	if total := dbScore + 1 + 25; score.Total != total {
+		t.Errorf("Expected total %v, got %v", total, score.Total)
+	}
+	if score.Level != RiskHigh {
+		t.Errorf("Expected high level, got %s", score.Level)
+	}
+}
+
+func TestScorePlan_ConfiguredEnvironment(t *testing.T) {
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{
+			{Address: "aws_instance.web", Type: "aws_instance", IsUpdate: true},
+		},
+	}
+
+	weights := DefaultScoreWeights()
+	weights.Environment = "prod"
+	score := ScorePlan(summary, nil, weights)
+
+	// update (2) x compute (1.5) x prod (2)
+	if score.Total != 6 {
+		t.Errorf("Expected total 6, got %v", score.Total)
+	}
+	if score.Level != RiskLow {
+		t.Errorf("Expected low level, got %s", score.Level)
+	}
+}
@@ -0,0 +1,128 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// DefaultUpgradeCorrelation is the number of changed resources of a
+// provider from which a version change is reported as their likely cause
+const DefaultUpgradeCorrelation = 10
+
+// AnalyzeVersionChanges compares the Terraform and provider versions of
+// the plan with a previous plan or lock file and warns when many changes
+// correlate with an upgrade. Resource schema upgrades visible within the
+// plan itself are reported even when previous is nil.
+func AnalyzeVersionChanges(current, previous *parser.PlanSummary, minChanges int) []Warning {
+	warnings := make([]Warning, 0)
+
+	if previous != nil {
+		if previous.TerraformVersion != "" && current.TerraformVersion != "" &&
+			previous.TerraformVersion != current.TerraformVersion {
+			warnings = append(warnings, Warning{
+				Rule:        "terraform-version-change",
+				Level:       RiskMedium,
+				Resource:    "terraform",
+				Message:     fmt.Sprintf("Terraform version changes %s → %s", previous.TerraformVersion, current.TerraformVersion),
+				Explanation: "New Terraform versions can change plan output and state format",
+			})
+		}
+
+		for _, p := range current.Providers {
+			old, ok := findProvider(previous.Providers, p.Source)
+			if !ok {
+				continue
+			}
+			oldVersion, newVersion := providerVersions(old, p)
+			if oldVersion == "" || newVersion == "" || oldVersion == newVersion {
+				continue
+			}
+
+			count := countChanges(current, func(c parser.ResourceChange) bool { return usesProvider(c, p) })
+			upgrade := fmt.Sprintf("%s provider %s → %s", p.Name, oldVersion, newVersion)
+			warnings = append(warnings, correlatedWarning("provider-upgrade", p.Source, "", upgrade, count, minChanges,
+				"Provider upgrades often change defaults and schemas; make sure these diffs are expected"))
+		}
+	}
+
+	types := make([]string, 0)
+	for t, planned := range current.PlannedSchemaVersions {
+		if prior, ok := current.PriorSchemaVersions[t]; ok && prior != planned {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		resourceType := t
+		count := countChanges(current, func(c parser.ResourceChange) bool { return c.Type == resourceType })
+		if count == 0 {
+			continue
+		}
+		upgrade := fmt.Sprintf("%s schema upgrade v%d → v%d", t, current.PriorSchemaVersions[t], current.PlannedSchemaVersions[t])
+		warnings = append(warnings, correlatedWarning("schema-upgrade", t, t, upgrade, count, minChanges,
+			"The provider migrates existing state to a new schema; attribute diffs may be artifacts of the migration"))
+	}
+
+	return warnings
+}
+
+func correlatedWarning(rule, resource, resourceType, upgrade string, count, minChanges int, explanation string) Warning {
+	if count >= minChanges {
+		return Warning{
+			Rule:        rule,
+			Level:       RiskHigh,
+			Resource:    resource,
+			Type:        resourceType,
+			Message:     fmt.Sprintf("%d updates are likely caused by %s", count, upgrade),
+			Explanation: explanation,
+		}
+	}
+	return Warning{
+		Rule:        rule,
+		Level:       RiskMedium,
+		Resource:    resource,
+		Type:        resourceType,
+		Message:     fmt.Sprintf("Version change: %s (%d related updates)", upgrade, count),
+		Explanation: explanation,
+	}
+}
+
+func findProvider(providers []parser.ProviderInfo, source string) (parser.ProviderInfo, bool) {
+	for _, p := range providers {
+		if p.Source == source {
+			return p, true
+		}
+	}
+	return parser.ProviderInfo{}, false
+}
+
+// providerVersions returns the locked versions of a provider when both
+// sides have one, and the constraints otherwise. A locked version is never
+// compared with a constraint: a lock file given for one side only would
+// report every provider as changed.
+func providerVersions(old, current parser.ProviderInfo) (string, string) {
+	if old.Version != "" && current.Version != "" {
+		return old.Version, current.Version
+	}
+	return old.VersionConstraint, current.VersionConstraint
+}
+
+// usesProvider matches on the full source address, as providers of
+// different namespaces can share a name
+func usesProvider(c parser.ResourceChange, p parser.ProviderInfo) bool {
+	return c.ProviderName == p.Source
+}
+
+// countChanges counts the updated and replaced resources matching the predicate
+func countChanges(summary *parser.PlanSummary, predicate func(parser.ResourceChange) bool) int {
+	count := 0
+	for _, c := range summary.Changes {
+		if (c.IsUpdate || c.IsReplace) && predicate(c) {
+			count++
+		}
+	}
+	return count
+}
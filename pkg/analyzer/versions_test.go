@@ -0,0 +1,137 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+const awsSource = "registry.terraform.io/hashicorp/aws"
+
+func awsUpdates(n int) []parser.ResourceChange {
+	changes := make([]parser.ResourceChange, 0, n)
+	for i := 0; i < n; i++ {
+		changes = append(changes, parser.ResourceChange{
+			Address:      fmt.Sprintf("aws_instance.web%d", i),
+			Type:         "aws_instance",
+			ProviderName: awsSource,
+			IsUpdate:     true,
+		})
+	}
+	return changes
+}
+
+func TestAnalyzeVersionChanges_ProviderUpgrade(t *testing.T) {
+	previous := &parser.PlanSummary{
+		Providers: []parser.ProviderInfo{{Source: awsSource, Name: "aws", Version: "5.31.0"}},
+	}
+	current := &parser.PlanSummary{
+		Providers: []parser.ProviderInfo{{Source: awsSource, Name: "aws", Version: "6.0.0"}},
+		Changes:   awsUpdates(12),
+	}
+
+	warnings := AnalyzeVersionChanges(current, previous, DefaultUpgradeCorrelation)
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %+v", len(warnings), warnings)
+	}
+	if warnings[0].Level != RiskHigh {
+		t.Errorf("Expected high risk, got %s", warnings[0].Level)
+	}
+	if warnings[0].Message != "12 updates are likely caused by aws provider 5.31.0 → 6.0.0" {
+		t.Errorf("Unexpected message: %s", warnings[0].Message)
+	}
+}
+
+func TestAnalyzeVersionChanges_FewChanges(t *testing.T) {
+	previous := &parser.PlanSummary{
+		TerraformVersion: "1.5.7",
+		Providers:        []parser.ProviderInfo{{Source: awsSource, Name: "aws", VersionConstraint: "~> 5.0"}},
+	}
+	current := &parser.PlanSummary{
+		TerraformVersion: "1.9.0",
+		Providers:        []parser.ProviderInfo{{Source: awsSource, Name: "aws", VersionConstraint: "~> 6.0"}},
+		Changes:          awsUpdates(2),
+	}
+
+	warnings := AnalyzeVersionChanges(current, previous, DefaultUpgradeCorrelation)
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %d: %+v", len(warnings), warnings)
+	}
+	for _, w := range warnings {
+		if w.Level != RiskMedium {
+			t.Errorf("Expected medium risk for %q, got %s", w.Message, w.Level)
+		}
+	}
+	if !strings.Contains(warnings[0].Message, "1.5.7 → 1.9.0") {
+		t.Errorf("Unexpected terraform version message: %s", warnings[0].Message)
+	}
+}
+
+func TestAnalyzeVersionChanges_LockFileOneSide(t *testing.T) {
+	// The same plan, with a lock file applied to the current side only
+	previous := &parser.PlanSummary{
+		Providers: []parser.ProviderInfo{{Source: awsSource, Name: "aws", VersionConstraint: "~> 5.0"}},
+	}
+	current := &parser.PlanSummary{
+		Providers: []parser.ProviderInfo{{Source: awsSource, Name: "aws", VersionConstraint: "~> 5.0", Version: "5.2.0"}},
+		Changes:   awsUpdates(2),
+	}
+
+	if warnings := AnalyzeVersionChanges(current, previous, DefaultUpgradeCorrelation); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
+
+	// Constraints are still compared when only one side is locked
+	current.Providers[0].VersionConstraint = "~> 6.0"
+	warnings := AnalyzeVersionChanges(current, previous, DefaultUpgradeCorrelation)
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "~> 5.0 → ~> 6.0") {
+		t.Errorf("Expected a constraint change, got %+v", warnings)
+	}
+}
+
+func TestAnalyzeVersionChanges_ProviderNamespace(t *testing.T) {
+	other := "registry.terraform.io/someorg/aws"
+	previous := &parser.PlanSummary{
+		Providers: []parser.ProviderInfo{
+			{Source: awsSource, Name: "aws", Version: "5.31.0"},
+			{Source: other, Name: "aws", Version: "1.0.0"},
+		},
+	}
+	current := &parser.PlanSummary{
+		Providers: []parser.ProviderInfo{
+			{Source: awsSource, Name: "aws", Version: "5.31.0"},
+			{Source: other, Name: "aws", Version: "2.0.0"},
+		},
+		Changes: awsUpdates(12),
+	}
+
+	warnings := AnalyzeVersionChanges(current, previous, DefaultUpgradeCorrelation)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %+v", len(warnings), warnings)
+	}
+	// The updates belong to hashicorp/aws, which didn't change
+	if warnings[0].Resource != other || warnings[0].Message != "Version change: aws provider 1.0.0 → 2.0.0 (0 related updates)" {
+		t.Errorf("Unexpected warning: %+v", warnings[0])
+	}
+}
+
+func TestAnalyzeVersionChanges_SchemaUpgrade(t *testing.T) {
+	current := &parser.PlanSummary{
+		Changes:               awsUpdates(10),
+		PriorSchemaVersions:   map[string]uint64{"aws_instance": 1, "aws_s3_bucket": 0},
+		PlannedSchemaVersions: map[string]uint64{"aws_instance": 2, "aws_s3_bucket": 0},
+	}
+
+	warnings := AnalyzeVersionChanges(current, nil, DefaultUpgradeCorrelation)
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %+v", len(warnings), warnings)
+	}
+	if warnings[0].Message != "10 updates are likely caused by aws_instance schema upgrade v1 → v2" {
+		t.Errorf("Unexpected message: %s", warnings[0].Message)
+	}
+}
@@ -0,0 +1,152 @@
+package compare
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// Result describes how a plan differs from an earlier plan of the same
+// configuration. Resources are matched by address. The values of added
+// and resolved changes are masked where Terraform marks them sensitive.
+type Result struct {
+	Added     []parser.ResourceChange `json:"added"`    // changes only planned now
+	Resolved  []parser.ResourceChange `json:"resolved"` // changes no longer planned
+	Changed   []ChangeDiff            `json:"changed"`  // changes planned differently
+	Unchanged int                     `json:"unchanged"`
+}
+
+// ChangeDiff is a resource change present in both plans with different
+// actions or planned values
+type ChangeDiff struct {
+	Address    string          `json:"address"`
+	Type       string          `json:"type"`
+	OldActions []string        `json:"old_actions"`
+	NewActions []string        `json:"new_actions"`
+	Attributes []AttributeDiff `json:"attributes"`
+}
+
+// AttributeDiff is a planned attribute value that differs between the
+// plans. Values of sensitive attributes are left out.
+type AttributeDiff struct {
+	Name       string      `json:"name"`
+	Old        interface{} `json:"old"`
+	New        interface{} `json:"new"`
+	OldUnknown bool        `json:"old_unknown,omitempty"` // known after apply in the old plan
+	NewUnknown bool        `json:"new_unknown,omitempty"`
+	Sensitive  bool        `json:"sensitive,omitempty"`
+}
+
+// HasDifferences reports whether the plans differ at all
+func (r *Result) HasDifferences() bool {
+	return len(r.Added) > 0 || len(r.Resolved) > 0 || len(r.Changed) > 0
+}
+
+// ActionsChanged reports whether the planned actions differ
+func (d ChangeDiff) ActionsChanged() bool {
+	return !reflect.DeepEqual(d.OldActions, d.NewActions)
+}
+
+// Plans compares the changes of two plans. Resources without changes in a
+// plan count as absent from it.
+func Plans(previous, current *parser.PlanSummary) *Result {
+	oldChanges := pendingChanges(previous)
+	newChanges := pendingChanges(current)
+
+	result := &Result{
+		Added:    make([]parser.ResourceChange, 0),
+		Resolved: make([]parser.ResourceChange, 0),
+		Changed:  make([]ChangeDiff, 0),
+	}
+
+	for address, n := range newChanges {
+		o, ok := oldChanges[address]
+		if !ok {
+			result.Added = append(result.Added, n.Masked())
+			continue
+		}
+
+		diff := ChangeDiff{
+			Address:    address,
+			Type:       n.Type,
+			OldActions: o.Actions,
+			NewActions: n.Actions,
+			Attributes: attributeDiffs(o, n),
+		}
+		if diff.ActionsChanged() || len(diff.Attributes) > 0 {
+			result.Changed = append(result.Changed, diff)
+		} else {
+			result.Unchanged++
+		}
+	}
+
+	for address, o := range oldChanges {
+		if _, ok := newChanges[address]; !ok {
+			result.Resolved = append(result.Resolved, o.Masked())
+		}
+	}
+
+	sort.Slice(result.Added, func(i, j int) bool { return result.Added[i].Address < result.Added[j].Address })
+	sort.Slice(result.Resolved, func(i, j int) bool { return result.Resolved[i].Address < result.Resolved[j].Address })
+	sort.Slice(result.Changed, func(i, j int) bool { return result.Changed[i].Address < result.Changed[j].Address })
+
+	return result
+}
+
+func pendingChanges(summary *parser.PlanSummary) map[string]parser.ResourceChange {
+	changes := make(map[string]parser.ResourceChange)
+	for _, c := range summary.Changes {
+		if !c.IsNoOp {
+			changes[c.Address] = c
+		}
+	}
+	return changes
+}
+
+// attributeDiffs compares the planned values of two changes of the same
+// resource, sorted by attribute name
+func attributeDiffs(previous, current parser.ResourceChange) []AttributeDiff {
+	keys := make(map[string]bool)
+	for k := range previous.After {
+		keys[k] = true
+	}
+	for k := range current.After {
+		keys[k] = true
+	}
+	for _, k := range previous.UnknownAttributes() {
+		keys[k] = true
+	}
+	for _, k := range current.UnknownAttributes() {
+		keys[k] = true
+	}
+
+	diffs := make([]AttributeDiff, 0)
+	for k := range keys {
+		old, planned := previous.AfterValue(k), current.AfterValue(k)
+		if old.Equal(planned) || isNull(old) && isNull(planned) {
+			continue
+		}
+
+		d := AttributeDiff{
+			Name:       k,
+			Old:        old.Raw,
+			New:        planned.Raw,
+			OldUnknown: old.State == parser.ValueUnknown,
+			NewUnknown: planned.State == parser.ValueUnknown,
+		}
+		if old.Sensitive || planned.Sensitive {
+			d.Sensitive = true
+			d.Old, d.New = nil, nil
+		}
+		diffs = append(diffs, d)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// isNull reports whether a planned value is null or not set at all
+func isNull(v parser.Value) bool {
+	return v.State == parser.ValueNull || v.State == parser.ValueAbsent
+}
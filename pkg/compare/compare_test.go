@@ -0,0 +1,118 @@
+package compare
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func change(address string, actions []string, after map[string]interface{}) parser.ResourceChange {
+	return parser.ResourceChange{
+		Address: address,
+		Type:    "test_resource",
+		Actions: actions,
+		After:   after,
+		IsNoOp:  len(actions) == 1 && actions[0] == "no-op",
+	}
+}
+
+func TestPlans(t *testing.T) {
+	previous := &parser.PlanSummary{Changes: []parser.ResourceChange{
+		change("test_resource.same", []string{"update"}, map[string]interface{}{"size": 1.0}),
+		change("test_resource.fixed", []string{"delete"}, nil),
+		change("test_resource.resized", []string{"update"}, map[string]interface{}{"size": 1.0}),
+		change("test_resource.replaced", []string{"update"}, map[string]interface{}{"size": 1.0}),
+		change("test_resource.noop", []string{"no-op"}, map[string]interface{}{"size": 1.0}),
+	}}
+	current := &parser.PlanSummary{Changes: []parser.ResourceChange{
+		change("test_resource.same", []string{"update"}, map[string]interface{}{"size": 1.0}),
+		change("test_resource.resized", []string{"update"}, map[string]interface{}{"size": 2.0}),
+		change("test_resource.replaced", []string{"delete", "create"}, map[string]interface{}{"size": 1.0}),
+		change("test_resource.noop", []string{"create"}, map[string]interface{}{"size": 1.0}),
+		change("test_resource.new", []string{"create"}, nil),
+	}}
+
+	result := Plans(previous, current)
+
+	if len(result.Added) != 2 || result.Added[0].Address != "test_resource.new" || result.Added[1].Address != "test_resource.noop" {
+		t.Errorf("Added = %v, want new and noop", addresses(result.Added))
+	}
+	if len(result.Resolved) != 1 || result.Resolved[0].Address != "test_resource.fixed" {
+		t.Errorf("Resolved = %v, want fixed", addresses(result.Resolved))
+	}
+	if len(result.Changed) != 2 {
+		t.Fatalf("Expected 2 changed, got %d", len(result.Changed))
+	}
+	if d := result.Changed[0]; d.Address != "test_resource.replaced" || !d.ActionsChanged() || len(d.Attributes) != 0 {
+		t.Errorf("Unexpected diff: %+v", d)
+	}
+	if d := result.Changed[1]; d.Address != "test_resource.resized" || d.ActionsChanged() || len(d.Attributes) != 1 {
+		t.Errorf("Unexpected diff: %+v", d)
+	}
+	if result.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", result.Unchanged)
+	}
+	if !result.HasDifferences() {
+		t.Error("HasDifferences() = false, want true")
+	}
+}
+
+func TestPlans_Identical(t *testing.T) {
+	plan := &parser.PlanSummary{Changes: []parser.ResourceChange{
+		change("test_resource.a", []string{"create"}, map[string]interface{}{"name": "a"}),
+	}}
+
+	if result := Plans(plan, plan); result.HasDifferences() {
+		t.Errorf("HasDifferences() = true for identical plans: %+v", result)
+	}
+}
+
+func TestAttributeDiffs_UnknownAndSensitive(t *testing.T) {
+	previous := change("test_resource.a", []string{"update"}, map[string]interface{}{"id": "abc", "secret": "old"})
+	previous.AfterSensitive = map[string]interface{}{"secret": true}
+	current := change("test_resource.a", []string{"update"}, map[string]interface{}{"secret": "new"})
+	current.AfterUnknown = map[string]interface{}{"id": true}
+	current.AfterSensitive = map[string]interface{}{"secret": true}
+
+	diffs := attributeDiffs(previous, current)
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 diffs, got %+v", diffs)
+	}
+	if d := diffs[0]; d.Name != "id" || d.OldUnknown || !d.NewUnknown {
+		t.Errorf("Unexpected id diff: %+v", d)
+	}
+	if d := diffs[1]; d.Name != "secret" || !d.Sensitive || d.Old != nil || d.New != nil {
+		t.Errorf("Sensitive values must be redacted: %+v", d)
+	}
+}
+
+func addresses(changes []parser.ResourceChange) []string {
+	result := make([]string, 0, len(changes))
+	for _, c := range changes {
+		result = append(result, c.Address)
+	}
+	return result
+}
+
+func TestPlans_MasksSensitiveValues(t *testing.T) {
+	secret := func(address, password string) parser.ResourceChange {
+		c := change(address, []string{"create"}, map[string]interface{}{"name": "db", "password": password})
+		c.AfterSensitive = map[string]interface{}{"password": true}
+		return c
+	}
+	previous := &parser.PlanSummary{Changes: []parser.ResourceChange{secret("test_resource.old", "hunter2")}}
+	current := &parser.PlanSummary{Changes: []parser.ResourceChange{secret("test_resource.new", "hunter3")}}
+
+	data, err := json.Marshal(Plans(previous, current))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), "hunter") {
+		t.Errorf("JSON reveals a sensitive value: %s", data)
+	}
+	if strings.Count(string(data), `"password":"(sensitive)"`) != 2 || !strings.Contains(string(data), `"name":"db"`) {
+		t.Errorf("expected passwords masked and other values kept: %s", data)
+	}
+}
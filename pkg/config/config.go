@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/formatter"
+	"github.com/kvizadsaderah/infrasync/pkg/notify"
+	"github.com/kvizadsaderah/infrasync/pkg/plugin"
+	"github.com/kvizadsaderah/infrasync/pkg/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is the configuration file picked up from the working
+// directory when --config is not given
+const DefaultFile = ".infrasync.yml"
+
+// Config holds the user configuration loaded from YAML
+type Config struct {
+	MassChange     analyzer.MassChangeConfig `yaml:"mass_change"`
+	RiskScore      analyzer.ScoreWeights     `yaml:"risk_score"`
+	Rules          []policy.RuleConfig       `yaml:"rules"`
+	Rego           policy.RegoConfig         `yaml:"rego"`
+	Plugins        []plugin.Config           `yaml:"plugins"`
+	AttributeOrder formatter.AttributeOrder  `yaml:"attribute_order"`
+	Notify         []notify.Config           `yaml:"notify"`
+}
+
+// Default returns the configuration used when no file is given
+func Default() *Config {
+	return &Config{
+		MassChange:     analyzer.DefaultMassChangeConfig(),
+		RiskScore:      analyzer.DefaultScoreWeights(),
+		AttributeOrder: formatter.DefaultAttributeOrder(),
+	}
+}
+
+// Load reads a YAML configuration file. Values not present in the file
+// keep their defaults, including individual entries of weight maps.
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", filename, err)
+	}
+	return cfg, nil
+}
+
+// LoadDefault loads DefaultFile if it exists, otherwise the defaults
+func LoadDefault() (*Config, error) {
+	if _, err := os.Stat(DefaultFile); errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	return Load(DefaultFile)
+}
+
+// Parse decodes YAML configuration on top of the defaults. Unknown keys are
+// rejected so that typos don't silently fall back to defaults.
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+)
+
+func TestParse_Empty(t *testing.T) {
+	cfg, err := Parse([]byte(""))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if cfg.MassChange != analyzer.DefaultMassChangeConfig() {
+		t.Errorf("Expected default mass change config, got %+v", cfg.MassChange)
+	}
+}
+
+func TestParse_OverridesKeepDefaults(t *testing.T) {
+	data := []byte(`
+mass_change:
+  plan:
+    delete_ratio: 0.1
+risk_score:
+  actions:
+    delete: 20
+  environment: prod
+  thresholds:
+    high: 40
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	defaults := analyzer.DefaultScoreWeights()
+
+	if cfg.MassChange.Plan.DeleteRatio != 0.1 {
+		t.Errorf("Expected delete ratio 0.1, got %v", cfg.MassChange.Plan.DeleteRatio)
+	}
+	if cfg.MassChange.Plan.Deletes != analyzer.DefaultMassChangeConfig().Plan.Deletes {
+		t.Errorf("Expected default delete count to be kept, got %d", cfg.MassChange.Plan.Deletes)
+	}
+	if cfg.RiskScore.Actions["delete"] != 20 {
+		t.Errorf("Expected delete weight 20, got %v", cfg.RiskScore.Actions["delete"])
+	}
+	if cfg.RiskScore.Actions["replace"] != defaults.Actions["replace"] {
+		t.Errorf("Expected default replace weight to be kept, got %v", cfg.RiskScore.Actions["replace"])
+	}
+	if cfg.RiskScore.Environment != "prod" {
+		t.Errorf("Expected environment prod, got %q", cfg.RiskScore.Environment)
+	}
+	if cfg.RiskScore.Thresholds.High != 40 || cfg.RiskScore.Thresholds.Critical != defaults.Thresholds.Critical {
+		t.Errorf("Unexpected thresholds: %+v", cfg.RiskScore.Thresholds)
+	}
+}
+
+func TestParse_UnknownField(t *testing.T) {
+	if _, err := Parse([]byte("mass_chnage:\n  min_group_size: 2\n")); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestParse_Rules(t *testing.T) {
+	data := []byte(`
+rules:
+  - id: prod-instance-type
+    match:
+      types: [aws_instance]
+      addresses: ["*prod*"]
+      actions: [update]
+    condition: before.instance_type != after.instance_type
+    severity: high
+    message: Instance type must not change in production
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(cfg.Rules) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(cfg.Rules))
+	}
+	r := cfg.Rules[0]
+	if r.ID != "prod-instance-type" || r.Severity != "high" || len(r.Match.Actions) != 1 {
+		t.Errorf("Unexpected rule: %+v", r)
+	}
+}
+
+func TestParse_Plugins(t *testing.T) {
+	data := []byte(`
+plugins:
+  - name: naming
+    command: ./bin/naming-check
+    args: [--strict]
+    env:
+      NAMING_PREFIX: acme-
+    timeout: 10s
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(cfg.Plugins) != 1 {
+		t.Fatalf("Expected 1 plugin, got %d", len(cfg.Plugins))
+	}
+	p := cfg.Plugins[0]
+	if p.Name != "naming" || p.Command != "./bin/naming-check" || p.Env["NAMING_PREFIX"] != "acme-" {
+		t.Errorf("Unexpected plugin: %+v", p)
+	}
+	if p.Timeout != 10*time.Second {
+		t.Errorf("Timeout = %v, want 10s", p.Timeout)
+	}
+}
+
+func TestParse_Notify(t *testing.T) {
+	data := []byte(`
+notify:
+  - name: security
+    url: https://hooks.example.com/{{ env "HOOK_ID" }}
+    format: slack
+    headers:
+      Authorization: Bearer {{ env "HOOK_TOKEN" }}
+    secret: "{{ env \"HOOK_SECRET\" }}"
+    when: [critical, deletes]
+    attempts: 5
+    backoff: 2s
+    timeout: 30s
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(cfg.Notify) != 1 {
+		t.Fatalf("Expected 1 webhook, got %d", len(cfg.Notify))
+	}
+	n := cfg.Notify[0]
+	if n.Name != "security" || n.Format != "slack" || n.Headers["Authorization"] != `Bearer {{ env "HOOK_TOKEN" }}` {
+		t.Errorf("Unexpected webhook: %+v", n)
+	}
+	if len(n.When) != 2 || n.When[0] != "critical" || n.When[1] != "deletes" {
+		t.Errorf("When = %v, want [critical deletes]", n.When)
+	}
+	if n.Attempts != 5 || n.Backoff != 2*time.Second || n.Timeout != 30*time.Second {
+		t.Errorf("Attempts, Backoff, Timeout = %d, %v, %v, want 5, 2s, 30s", n.Attempts, n.Backoff, n.Timeout)
+	}
+}
+
+func TestParse_AttributeOrder(t *testing.T) {
+	data := []byte(`
+attribute_order:
+  important_first: true
+  important:
+    aws_instance: [instance_type]
+    custom_widget: [size]
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	order := cfg.AttributeOrder
+	if !order.ImportantFirst {
+		t.Error("Expected important_first to be enabled")
+	}
+	if got := order.Important["aws_instance"]; len(got) != 1 || got[0] != "instance_type" {
+		t.Errorf("aws_instance = %v, want [instance_type]", got)
+	}
+	if got := order.Important["custom_widget"]; len(got) != 1 {
+		t.Errorf("custom_widget = %v, want [size]", got)
+	}
+	// Types not mentioned keep their defaults
+	if len(order.Important["aws_db_instance"]) == 0 {
+		t.Error("Expected default important attributes for aws_db_instance to be kept")
+	}
+}
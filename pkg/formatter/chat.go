@@ -0,0 +1,214 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// Warnings listed in chat messages; the others are only counted
+const chatTopWarnings = 5
+
+// chatMessage is the content of a chat message, shared by the Slack and
+// Teams formats. Warnings and changes of named stacks are prefixed with
+// the stack name.
+type chatMessage struct {
+	counts           parser.PlanSummary  // of all stacks
+	stacks           []chatStack         // empty for a single plan
+	score            *analyzer.PlanScore // of a single plan
+	warnings         []analyzer.Warning  // highest level first
+	destructive      []string            // deleted and replaced resources
+	terraformVersion string
+}
+
+type chatStack struct {
+	name   string
+	counts parser.PlanSummary
+	score  *analyzer.PlanScore
+}
+
+func newChatMessage(stacks []Stack) *chatMessage {
+	m := &chatMessage{}
+	named := len(stacks) > 1
+	for _, s := range stacks {
+		named = named || s.Name != ""
+	}
+
+	for _, s := range stacks {
+		prefix := ""
+		if named {
+			prefix = s.Name + ": "
+			m.stacks = append(m.stacks, chatStack{name: s.Name, counts: *s.Summary, score: s.Score})
+		} else {
+			m.score = s.Score
+		}
+		if m.terraformVersion == "" {
+			m.terraformVersion = s.Summary.TerraformVersion
+		}
+
+		m.counts.ToCreate += s.Summary.ToCreate
+		m.counts.ToUpdate += s.Summary.ToUpdate
+		m.counts.ToReplace += s.Summary.ToReplace
+		m.counts.ToDelete += s.Summary.ToDelete
+		m.counts.ToRead += s.Summary.ToRead
+
+		for _, w := range append(append([]analyzer.Warning{}, s.PlanWarnings...), s.Warnings...) {
+			if w.Resource != "" {
+				w.Resource = prefix + w.Resource
+			}
+			m.warnings = append(m.warnings, w)
+		}
+		for _, c := range s.Summary.Changes {
+			switch {
+			case c.IsDelete:
+				m.destructive = append(m.destructive, "- "+prefix+c.Address)
+			case c.IsReplace:
+				m.destructive = append(m.destructive, "-/+ "+prefix+c.Address)
+			}
+		}
+	}
+
+	sort.SliceStable(m.warnings, func(i, j int) bool {
+		return levelRank(m.warnings[i].Level) < levelRank(m.warnings[j].Level)
+	})
+	return m
+}
+
+// headline summarizes the message in one line, for notifications
+func (m *chatMessage) headline() string {
+	parts := make([]string, 0)
+	for _, p := range []struct {
+		n    int
+		verb string
+	}{
+		{m.counts.ToCreate, "create"},
+		{m.counts.ToUpdate, "update"},
+		{m.counts.ToReplace, "replace"},
+		{m.counts.ToDelete, "destroy"},
+	} {
+		if p.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d to %s", p.n, p.verb))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no changes")
+	}
+
+	s := "Terraform plan: " + strings.Join(parts, ", ")
+	if n := len(filterWarnings(m.warnings, analyzer.RiskCritical)); n > 0 {
+		s += fmt.Sprintf(" • %d critical %s", n, pluralize(n, "warning"))
+	}
+	return s
+}
+
+// topWarnings returns the warnings listed and the number left out
+func (m *chatMessage) topWarnings() ([]analyzer.Warning, int) {
+	if len(m.warnings) <= chatTopWarnings {
+		return m.warnings, 0
+	}
+	return m.warnings[:chatTopWarnings], len(m.warnings) - chatTopWarnings
+}
+
+// footer credits InfraSync and names the Terraform version
+func (m *chatMessage) footer() string {
+	s := "Generated by InfraSync"
+	if m.terraformVersion != "" {
+		s += " • Terraform " + m.terraformVersion
+	}
+	return s
+}
+
+func levelRank(l analyzer.RiskLevel) int {
+	switch l {
+	case analyzer.RiskCritical:
+		return 0
+	case analyzer.RiskHigh:
+		return 1
+	case analyzer.RiskMedium:
+		return 2
+	case analyzer.RiskLow:
+		return 3
+	}
+	return 4
+}
+
+func levelIcon(l analyzer.RiskLevel) string {
+	switch l {
+	case analyzer.RiskCritical:
+		return "🚨"
+	case analyzer.RiskHigh:
+		return "⚠️"
+	case analyzer.RiskMedium:
+		return "ℹ️"
+	}
+	return "•"
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// scoreText renders a risk score, e.g. "72 (high)"
+func scoreText(s *analyzer.PlanScore) string {
+	return fmt.Sprintf("%.0f (%s)", s.Total, s.Level)
+}
+
+// countsText renders the non-zero action counts, e.g. "+2 ~1 ⟳1 -1"
+func countsText(counts parser.PlanSummary) string {
+	return (&MarkdownFormatter{}).FormatCompact(&counts)
+}
+
+// clip cuts s to at most n bytes at a character boundary, ending with …
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := max(n-len("…"), 0)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// packLines joins lines into at most n texts of at most size bytes each.
+// Lines that don't fit are left out and counted by more, appended to the
+// last text.
+func packLines(lines []string, size, n int, more func(left int) string) []string {
+	reserve := len(more(len(lines))) + 1
+	texts := make([]string, 0)
+	current := ""
+	for i, line := range lines {
+		line = clip(line, size-reserve)
+		if current != "" && len(current)+1+len(line) > size-reserve {
+			if len(texts)+1 == n {
+				return append(texts, current+"\n"+more(len(lines)-i))
+			}
+			texts = append(texts, current)
+			current = ""
+		}
+		if current != "" {
+			current += "\n"
+		}
+		current += line
+	}
+	if current != "" {
+		texts = append(texts, current)
+	}
+	return texts
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,176 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestChatFormatters_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		format func(*bytes.Buffer) error
+	}{
+		{"slack_empty", func(b *bytes.Buffer) error { return (&SlackFormatter{}).Format(b, loadPlan(t, "empty.json")) }},
+		{"slack_plan", func(b *bytes.Buffer) error {
+			f := &SlackFormatter{ArtifactURL: "https://ci.example.com/plan.md", Analysis: testAnalysis()}
+			return f.Format(b, loadPlan(t, "mixed.json"))
+		}},
+		{"slack_stacks", func(b *bytes.Buffer) error { return (&SlackFormatter{}).FormatStacks(b, testStacks(t)) }},
+		{"teams_empty", func(b *bytes.Buffer) error { return (&TeamsFormatter{}).Format(b, loadPlan(t, "empty.json")) }},
+		{"teams_plan", func(b *bytes.Buffer) error {
+			f := &TeamsFormatter{ArtifactURL: "https://ci.example.com/plan.md", Analysis: testAnalysis()}
+			return f.Format(b, loadPlan(t, "mixed.json"))
+		}},
+		{"teams_stacks", func(b *bytes.Buffer) error { return (&TeamsFormatter{}).FormatStacks(b, testStacks(t)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.format(&buf); err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			if !json.Valid(buf.Bytes()) {
+				t.Fatalf("invalid JSON:\n%s", buf.String())
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+// destructivePlan deletes and replaces n resources each
+func destructivePlan(n int) *parser.PlanSummary {
+	summary := &parser.PlanSummary{TerraformVersion: "1.9.0", ToDelete: n, ToReplace: n}
+	for i := 0; i < n; i++ {
+		summary.Changes = append(summary.Changes,
+			parser.ResourceChange{Address: fmt.Sprintf("aws_instance.web[%d]", i), Type: "aws_instance", IsDelete: true},
+			parser.ResourceChange{Address: fmt.Sprintf("aws_security_group.app[%d]", i), Type: "aws_security_group", IsReplace: true})
+	}
+	return summary
+}
+
+func TestSlackFormatter_Limits(t *testing.T) {
+	summary := destructivePlan(2000)
+
+	var buf bytes.Buffer
+	if err := (&SlackFormatter{Analysis: testAnalysis()}).Format(&buf, summary); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var msg slackMessage
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	blocks := append([]slackBlock{}, msg.Blocks...)
+	for _, a := range msg.Attachments {
+		if len(a.Blocks) > 50 {
+			t.Errorf("attachment has %d blocks, limit 50", len(a.Blocks))
+		}
+		blocks = append(blocks, a.Blocks...)
+	}
+	if len(msg.Blocks) > 50 {
+		t.Errorf("message has %d blocks, limit 50", len(msg.Blocks))
+	}
+	for _, b := range blocks {
+		if b.Text != nil && len(b.Text.Text) > slackMaxText {
+			t.Errorf("%s block text is %d bytes long, limit %d", b.Type, len(b.Text.Text), slackMaxText)
+		}
+	}
+
+	last := msg.Attachments[0].Blocks[len(msg.Attachments[0].Blocks)-1].Text.Text
+	if !strings.Contains(last, "more") || strings.Count(last, "```") != 2 {
+		t.Errorf("expected the list to end with the number of changes left out in a closed code block, got %q", last[max(len(last)-100, 0):])
+	}
+}
+
+func TestTeamsFormatter_Limits(t *testing.T) {
+	summary := destructivePlan(2000)
+
+	var buf bytes.Buffer
+	if err := (&TeamsFormatter{}).Format(&buf, summary); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if buf.Len() > teamsMaxBytes {
+		t.Errorf("message is %d bytes long, limit %d", buf.Len(), teamsMaxBytes)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Fatal("invalid JSON")
+	}
+	if !strings.Contains(buf.String(), "more\"") || !strings.Contains(buf.String(), "Destructive Changes (4000)") {
+		t.Error("expected the changes left out to be counted")
+	}
+	if buf.Len() < teamsMaxBytes*9/10 {
+		t.Errorf("message is only %d bytes long, expected as many changes as fit", buf.Len())
+	}
+}
+
+func TestTeamsFormatter_StacksLimits(t *testing.T) {
+	// Many stacks with long names and long warnings: leaving out the
+	// destructive changes alone doesn't fit the card
+	stacks := make([]Stack, 0, 400)
+	for i := 0; i < 400; i++ {
+		name := fmt.Sprintf("live/prod/eu-west-1/%s/unit-%d", strings.Repeat("service-", 40), i)
+		warnings := []analyzer.Warning{{
+			Level:    analyzer.RiskCritical,
+			Resource: fmt.Sprintf("aws_db_instance.main[%d]", i),
+			Message:  strings.Repeat("Database will be DELETED ", 400),
+		}}
+		stacks = append(stacks, Stack{Name: name, Summary: destructivePlan(3), Analysis: Analysis{Warnings: warnings}})
+	}
+
+	var buf bytes.Buffer
+	if err := (&TeamsFormatter{}).FormatStacks(&buf, stacks); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var msg teamsMessage
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !fitsTeams(msg) || buf.Len() > teamsMaxBytes {
+		t.Errorf("message is %d bytes long, limit %d", buf.Len(), teamsMaxBytes)
+	}
+	for _, want := range []string{"more stacks", "Destructive Changes (2400)", "Top Warnings", "more warnings"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in the message", want)
+		}
+	}
+}
+
+func TestPackLines(t *testing.T) {
+	more := func(left int) string { return fmt.Sprintf("+%d", left) }
+	lines := []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"}
+
+	tests := []struct {
+		name string
+		size int
+		n    int
+		want []string
+	}{
+		{"all in one", 100, 3, []string{"aaaa\nbbbb\ncccc\ndddd\neeee"}},
+		{"several", 13, 3, []string{"aaaa\nbbbb", "cccc\ndddd", "eeee"}},
+		{"left out", 13, 2, []string{"aaaa\nbbbb", "cccc\ndddd\n+1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := packLines(lines, tt.size, tt.n, more)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("packLines() = %q, want %q", got, tt.want)
+			}
+			for _, text := range got {
+				if len(text) > tt.size {
+					t.Errorf("%q is longer than %d bytes", text, tt.size)
+				}
+			}
+		})
+	}
+
+	if got := packLines([]string{"abcdefgh"}, 8, 1, more); len(got) != 1 || got[0] != "ab…" {
+		t.Errorf("packLines() = %q, want a clipped line leaving room for the count", got)
+	}
+}
@@ -2,9 +2,11 @@ package formatter
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
 	"github.com/kvizadsaderah/infrasync/pkg/parser"
 )
 
@@ -12,6 +14,10 @@ import (
 type CLIFormatter struct {
 	ShowUnchanged bool
 	Verbose       bool
+	Color         ColorMode
+	Order         AttributeOrder
+	GroupBy       GroupBy
+	Analysis
 }
 
 // NewCLIFormatter creates a new CLI formatter
@@ -22,69 +28,127 @@ func NewCLIFormatter(showUnchanged, verbose bool) *CLIFormatter {
 	}
 }
 
-// Format outputs the plan summary to terminal with colors
-func (f *CLIFormatter) Format(summary *parser.PlanSummary) {
-	// Print header
-	fmt.Printf("\n")
-	color.Cyan("═══════════════════════════════════════════════════════")
-	color.Cyan("  Terraform Plan Summary")
-	color.Cyan("═══════════════════════════════════════════════════════")
-	fmt.Printf("\n")
+// Format writes the plan summary and analysis results to w, colored when
+// the color mode allows it for w
+func (f *CLIFormatter) Format(w io.Writer, summary *parser.PlanSummary) error {
+	t := &terminal{w: w, color: f.Color.Enabled(w)}
+
+	// Plan-wide anomalies go above everything else
+	f.printPlanBanner(t)
 
-	fmt.Printf("Terraform Version: %s\n", summary.TerraformVersion)
-	fmt.Printf("Format Version: %s\n\n", summary.FormatVersion)
+	f.printSummary(t, summary, "Terraform Plan Summary")
+
+	f.printWarnings(t)
+	f.printMovedBlocks(t)
+	f.printDestructiveWarning(t, summary)
+
+	return t.err
+}
+
+func (f *CLIFormatter) printSummary(t *terminal, summary *parser.PlanSummary, title string) {
+	// Print header
+	t.printf(plain, "\n")
+	t.println(cyan, "═══════════════════════════════════════════════════════")
+	t.println(cyan, "  %s", title)
+	t.println(cyan, "═══════════════════════════════════════════════════════")
+	t.printf(plain, "\n")
+
+	t.printf(plain, "Terraform Version: %s\n", summary.TerraformVersion)
+	t.printf(plain, "Format Version: %s\n", summary.FormatVersion)
+	if f.Score != nil {
+		f.printRiskScore(t)
+	}
+	t.printf(plain, "\n")
 
 	// Print statistics
-	f.printStatistics(summary)
-	fmt.Printf("\n")
+	f.printStatistics(t, summary)
+	t.printf(plain, "\n")
+
+	// Print changes, grouped by action or as requested
+	switch {
+	case len(summary.Changes) == 0 && summary.Total != nil:
+		t.println(green, "✓ No changes match the filter.")
+	case len(summary.Changes) == 0:
+		t.println(green, "✓ No changes detected. Infrastructure is up-to-date.")
+	case f.GroupBy == "" || f.GroupBy == GroupByAction:
+		f.printChangesByType(t, summary)
+	default:
+		f.printChangesGrouped(t, summary)
+	}
 
-	// Print changes
-	if len(summary.Changes) == 0 {
-		color.Green("✓ No changes detected. Infrastructure is up-to-date.")
-		return
+	f.printDataSourceReads(t, summary)
+}
+
+func (f *CLIFormatter) printRiskScore(t *terminal) {
+	scoreStyle := green
+	switch f.Score.Level {
+	case analyzer.RiskCritical:
+		scoreStyle = boldRed
+	case analyzer.RiskHigh:
+		scoreStyle = boldYellow
+	case analyzer.RiskMedium:
+		scoreStyle = cyan
 	}
 
-	// Group and print changes by type
-	f.printChangesByType(summary)
+	t.printf(plain, "Risk Score: ")
+	t.println(scoreStyle, "%.0f (%s)", f.Score.Total, f.Score.Level)
+
+	modules := sortedModuleScores(f.Score)
+	if len(modules) < 2 {
+		return
+	}
+	if len(modules) > 5 && !f.Verbose {
+		modules = modules[:5]
+	}
+	for _, m := range modules {
+		t.println(hiBlack, "  %s: %.0f", moduleLabel(m.Module), m.Score)
+	}
 }
 
-func (f *CLIFormatter) printStatistics(summary *parser.PlanSummary) {
-	color.Cyan("Changes Overview:")
-	color.Cyan("─────────────────")
+func (f *CLIFormatter) printStatistics(t *terminal, summary *parser.PlanSummary) {
+	t.println(cyan, "Changes Overview:")
+	t.println(cyan, "─────────────────")
 
 	if summary.ToCreate > 0 {
-		color.Green("  ✓ %d to create", summary.ToCreate)
+		t.println(green, "  ✓ %d to create", summary.ToCreate)
 	}
 	if summary.ToUpdate > 0 {
-		color.Yellow("  ~ %d to update", summary.ToUpdate)
+		t.println(yellow, "  ~ %d to update", summary.ToUpdate)
 	}
 	if summary.ToReplace > 0 {
-		color.Magenta("  ⟳ %d to replace", summary.ToReplace)
+		t.println(magenta, "  ⟳ %d to replace", summary.ToReplace)
 	}
 	if summary.ToDelete > 0 {
-		color.Red("  ✗ %d to destroy", summary.ToDelete)
+		t.println(red, "  ✗ %d to destroy", summary.ToDelete)
+	}
+	if summary.ToRead > 0 {
+		t.println(cyan, "  <= %d data source(s) to read during apply", summary.ToRead)
 	}
 	if summary.NoChanges > 0 && f.ShowUnchanged {
-		color.White("  • %d unchanged", summary.NoChanges)
+		t.println(white, "  • %d unchanged", summary.NoChanges)
 	}
 
 	total := summary.ToCreate + summary.ToUpdate + summary.ToReplace + summary.ToDelete
 	if total == 0 && summary.NoChanges > 0 {
-		color.Green("\n  All resources are up-to-date!")
+		t.println(green, "\n  All resources are up-to-date!")
+	}
+
+	if summary.Total != nil {
+		t.println(hiBlack, "  (filtered: %d of %d changed resources)", total, summary.Total.Changed())
 	}
 }
 
-func (f *CLIFormatter) printChangesByType(summary *parser.PlanSummary) {
+func (f *CLIFormatter) printChangesByType(t *terminal, summary *parser.PlanSummary) {
 	// Print creates
 	creates := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsCreate })
 	if len(creates) > 0 {
-		color.Green("\n✓ Resources to CREATE (%d):", len(creates))
-		color.Green("────────────────────────────")
+		t.println(green, "\n✓ Resources to CREATE (%d):", len(creates))
+		t.println(green, "────────────────────────────")
 		for _, c := range creates {
-			color.Green("  + %s", c.Address)
-			color.HiBlack("    Type: %s", c.Type)
+			t.println(green, "  + %s", c.Address)
+			t.println(hiBlack, "    Type: %s", c.Type)
 			if f.Verbose {
-				f.printAttributes(c.After, "    ", color.HiGreen)
+				f.printAttributes(t, c, true, "    ", hiGreen)
 			}
 		}
 	}
@@ -92,26 +156,26 @@ func (f *CLIFormatter) printChangesByType(summary *parser.PlanSummary) {
 	// Print updates
 	updates := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsUpdate })
 	if len(updates) > 0 {
-		color.Yellow("\n~ Resources to UPDATE (%d):", len(updates))
-		color.Yellow("────────────────────────────")
+		t.println(yellow, "\n~ Resources to UPDATE (%d):", len(updates))
+		t.println(yellow, "────────────────────────────")
 		for _, c := range updates {
-			color.Yellow("  ~ %s", c.Address)
-			color.HiBlack("    Type: %s", c.Type)
-			f.printAttributeDiff(c.Before, c.After, c.AfterUnknown, c.BeforeSensitive, c.AfterSensitive, "    ")
+			t.println(yellow, "  ~ %s", c.Address)
+			t.println(hiBlack, "    Type: %s", c.Type)
+			f.printAttributeDiff(t, c, "    ")
 		}
 	}
 
 	// Print replaces
 	replaces := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsReplace })
 	if len(replaces) > 0 {
-		color.Magenta("\n⟳ Resources to REPLACE (%d):", len(replaces))
-		color.Magenta("────────────────────────────")
+		t.println(magenta, "\n⟳ Resources to REPLACE (%d):", len(replaces))
+		t.println(magenta, "────────────────────────────")
 		for _, c := range replaces {
-			color.Magenta("  ⟳ %s", c.Address)
-			color.HiBlack("    Type: %s", c.Type)
-			color.HiYellow("    ⚠ This resource will be destroyed and recreated")
+			t.println(magenta, "  ⟳ %s", c.Address)
+			t.println(hiBlack, "    Type: %s", c.Type)
+			t.println(hiYellow, "    ⚠ This resource will be destroyed and recreated")
 			if f.Verbose {
-				f.printAttributeDiff(c.Before, c.After, c.AfterUnknown, c.BeforeSensitive, c.AfterSensitive, "    ")
+				f.printAttributeDiff(t, c, "    ")
 			}
 		}
 	}
@@ -119,18 +183,36 @@ func (f *CLIFormatter) printChangesByType(summary *parser.PlanSummary) {
 	// Print deletes
 	deletes := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsDelete })
 	if len(deletes) > 0 {
-		color.Red("\n✗ Resources to DESTROY (%d):", len(deletes))
-		color.Red("────────────────────────────")
+		t.println(red, "\n✗ Resources to DESTROY (%d):", len(deletes))
+		t.println(red, "────────────────────────────")
 		for _, c := range deletes {
-			color.Red("  - %s", c.Address)
-			color.HiBlack("    Type: %s", c.Type)
+			t.println(red, "  - %s", c.Address)
+			t.println(hiBlack, "    Type: %s", c.Type)
 			if f.Verbose {
-				f.printAttributes(c.Before, "    ", color.HiRed)
+				f.printAttributes(t, c, false, "    ", hiRed)
 			}
 		}
 	}
 
-	fmt.Printf("\n")
+	t.printf(plain, "\n")
+}
+
+// printDataSourceReads lists the data sources that can only be read during
+// apply
+func (f *CLIFormatter) printDataSourceReads(t *terminal, summary *parser.PlanSummary) {
+	reads := f.filterChanges(summary.DataSources, func(c parser.ResourceChange) bool { return c.IsRead })
+	if len(reads) == 0 {
+		return
+	}
+
+	t.println(cyan, "\n<= Data sources READ during apply (%d):", len(reads))
+	t.println(cyan, "────────────────────────────")
+	for _, c := range reads {
+		t.println(cyan, "  <= %s", c.Address)
+		t.println(hiBlack, "    Type: %s", c.Type)
+	}
+	t.println(hiYellow, "  ⚠ Their inputs are only known during apply, often because a dependency changes")
+	t.printf(plain, "\n")
 }
 
 func (f *CLIFormatter) filterChanges(changes []parser.ResourceChange, predicate func(parser.ResourceChange) bool) []parser.ResourceChange {
@@ -143,80 +225,54 @@ func (f *CLIFormatter) filterChanges(changes []parser.ResourceChange, predicate
 	return result
 }
 
-func (f *CLIFormatter) printAttributes(attrs map[string]interface{}, indent string, colorFunc func(format string, a ...interface{})) {
-	for key, val := range attrs {
-		valStr := formatValue(val)
-		if valStr != "" {
-			colorFunc("%s  %s: %s", indent, key, valStr)
-		}
+// printAttributes writes the attributes of one side of a change
+func (f *CLIFormatter) printAttributes(t *terminal, change parser.ResourceChange, after bool, indent string, s style) {
+	for _, a := range f.Order.attributeValues(change, after) {
+		t.println(s, "%s  %s: %s", indent, a.Key, valueString(a.Value))
 	}
 }
 
-func (f *CLIFormatter) printAttributeDiff(before, after map[string]interface{}, afterUnknown, beforeSensitive, afterSensitive interface{}, indent string) {
-	afterUnknownMap := toMap(afterUnknown)
-
-	// Collect all keys
-	allKeys := make(map[string]bool)
-	for k := range before {
-		allKeys[k] = true
-	}
-	for k := range after {
-		allKeys[k] = true
-	}
-
-	for key := range allKeys {
-		beforeVal, existsBefore := before[key]
-		afterVal, existsAfter := after[key]
-		_, isUnknown := afterUnknownMap[key]
-
-		isSensitive := f.isSensitiveKey(key, beforeSensitive, afterSensitive)
-
-		if isUnknown {
-			color.Cyan("%s  • %s: (known after apply)", indent, key)
-		} else if existsBefore && existsAfter {
-			beforeStr := formatValue(beforeVal)
-			afterStr := formatValue(afterVal)
-
-			if isSensitive {
-				beforeStr = "(sensitive)"
-				afterStr = "(sensitive)"
-			}
+func (f *CLIFormatter) printAttributeDiff(t *terminal, change parser.ResourceChange, indent string) {
+	for _, a := range f.Order.attributeChanges(change) {
+		if d, ok := diffString(a); ok {
+			f.printStringDiff(t, a.Key, d, indent)
+			continue
+		}
 
-			if beforeStr != afterStr {
-				color.Yellow("%s  ~ %s: %s → %s", indent, key, beforeStr, afterStr)
-			}
-		} else if existsAfter {
-			afterStr := formatValue(afterVal)
-			if isSensitive {
-				afterStr = "(sensitive)"
-			}
-			color.Green("%s  + %s: %s", indent, key, afterStr)
-		} else if existsBefore {
-			beforeStr := formatValue(beforeVal)
-			if isSensitive {
-				beforeStr = "(sensitive)"
-			}
-			color.Red("%s  - %s: %s", indent, key, beforeStr)
+		s := yellow
+		switch {
+		case a.After.State == parser.ValueUnknown:
+			s = cyan
+		case a.Op == "+":
+			s = green
+		case a.Op == "-":
+			s = red
 		}
+		t.println(s, "%s  %s", indent, a)
 	}
 }
 
-func (f *CLIFormatter) isSensitiveKey(key string, beforeSensitive, afterSensitive interface{}) bool {
-	if bm, ok := beforeSensitive.(map[string]interface{}); ok {
-		if val, exists := bm[key]; exists {
-			if b, ok := val.(bool); ok && b {
-				return true
-			}
-		}
+// printStringDiff writes the hunks of a long or multiline string change
+func (f *CLIFormatter) printStringDiff(t *terminal, key string, d stringDiff, indent string) {
+	if len(d.Hunks) == 0 {
+		t.println(yellow, "%s  ~ %s: (%s, formatting changes only)", indent, key, d.Kind)
+		return
 	}
-	if am, ok := afterSensitive.(map[string]interface{}); ok {
-		if val, exists := am[key]; exists {
-			if b, ok := val.(bool); ok && b {
-				return true
+
+	t.println(yellow, "%s  ~ %s: (%s)", indent, key, d.Kind)
+	for _, h := range d.Hunks {
+		t.println(cyan, "%s      %s", indent, h.Header())
+		for _, l := range h.Lines {
+			s := plain
+			switch l.op {
+			case "+":
+				s = green
+			case "-":
+				s = red
 			}
+			t.println(s, "%s      %s%s", indent, l.op, l.text)
 		}
 	}
-	return false
 }
 
 func formatValue(v interface{}) string {
@@ -247,34 +303,25 @@ func formatValue(v interface{}) string {
 	}
 }
 
-func toMap(v interface{}) map[string]interface{} {
-	if v == nil {
-		return make(map[string]interface{})
-	}
-	if m, ok := v.(map[string]interface{}); ok {
-		return m
-	}
-	return make(map[string]interface{})
-}
-
-// PrintWarnings prints any important warnings about the changes
-func (f *CLIFormatter) PrintWarnings(summary *parser.PlanSummary) {
+// printDestructiveWarning closes the output with a reminder about deletions
+// and replacements
+func (f *CLIFormatter) printDestructiveWarning(t *terminal, summary *parser.PlanSummary) {
 	hasDestructions := summary.ToDelete > 0 || summary.ToReplace > 0
 
 	if hasDestructions {
-		fmt.Printf("\n")
-		color.New(color.BgRed, color.FgWhite, color.Bold).Printf(" ⚠ WARNING ")
-		color.Red(" This plan includes destructive changes!")
+		t.printf(plain, "\n")
+		t.printf(redBanner, " ⚠ WARNING ")
+		t.println(red, " This plan includes destructive changes!")
 
 		if summary.ToDelete > 0 {
-			color.Red("  → %d resource(s) will be DESTROYED", summary.ToDelete)
+			t.println(red, "  → %d resource(s) will be DESTROYED", summary.ToDelete)
 		}
 		if summary.ToReplace > 0 {
-			color.Red("  → %d resource(s) will be REPLACED (destroyed and recreated)", summary.ToReplace)
+			t.println(red, "  → %d resource(s) will be REPLACED (destroyed and recreated)", summary.ToReplace)
 		}
 
-		color.Yellow("\n  Please review carefully before applying.")
-		fmt.Printf("\n")
+		t.println(yellow, "\n  Please review carefully before applying.")
+		t.printf(plain, "\n")
 	}
 }
 
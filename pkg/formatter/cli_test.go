@@ -0,0 +1,87 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCLIFormatter_Golden(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      string
+		formatter *CLIFormatter
+	}{
+		{"cli_empty", "empty.json", &CLIFormatter{}},
+		{"cli_plan", "mixed.json", &CLIFormatter{}},
+		{"cli_analysis", "mixed.json", &CLIFormatter{ShowUnchanged: true, Analysis: testAnalysis()}},
+		{"cli_color", "mixed.json", &CLIFormatter{Color: ColorAlways, Analysis: testAnalysis()}},
+		{"cli_group_module", "modules.json", &CLIFormatter{GroupBy: GroupByModule}},
+		{"cli_group_module_verbose", "modules.json", &CLIFormatter{GroupBy: GroupByModule, Verbose: true}},
+		{"cli_group_provider", "modules.json", &CLIFormatter{GroupBy: GroupByProvider}},
+		{"cli_data_sources", "datasources.json", &CLIFormatter{}},
+		{"cli_values", "values.json", &CLIFormatter{Verbose: true}},
+		{"cli_strings", "strings.json", &CLIFormatter{Verbose: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.formatter.Format(&buf, loadPlan(t, tt.plan)); err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+func TestCLIFormatter_NoColorForBuffers(t *testing.T) {
+	var buf bytes.Buffer
+	f := &CLIFormatter{Analysis: testAnalysis()}
+	if err := f.Format(&buf, loadPlan(t, "mixed.json")); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Error("Expected no escape sequences when writing to a buffer")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, bytes.ErrTooLarge
+}
+
+func TestCLIFormatter_WriteError(t *testing.T) {
+	f := &CLIFormatter{}
+	if err := f.Format(failingWriter{}, loadPlan(t, "mixed.json")); err != bytes.ErrTooLarge {
+		t.Errorf("Format() error = %v, want %v", err, bytes.ErrTooLarge)
+	}
+}
+
+func TestCLIFormatter_AttributeOrderGolden(t *testing.T) {
+	important := DefaultAttributeOrder()
+	important.ImportantFirst = true
+
+	tests := []struct {
+		name      string
+		formatter *CLIFormatter
+	}{
+		{"cli_attributes_sorted", &CLIFormatter{Verbose: true}},
+		{"cli_attributes_important", &CLIFormatter{Verbose: true, Order: important}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order is random, so a single run could pass by chance
+			for i := 0; i < 20; i++ {
+				var buf bytes.Buffer
+				if err := tt.formatter.Format(&buf, loadPlan(t, "attributes.json")); err != nil {
+					t.Fatalf("Format failed: %v", err)
+				}
+				assertGolden(t, tt.name, buf.Bytes())
+			}
+		})
+	}
+}
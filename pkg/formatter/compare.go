@@ -0,0 +1,155 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/compare"
+)
+
+// FormatComparison writes the differences between two plans to w
+func (f *CLIFormatter) FormatComparison(w io.Writer, result *compare.Result) error {
+	t := &terminal{w: w, color: f.Color.Enabled(w)}
+
+	t.printf(plain, "\n")
+	t.println(cyan, "═══════════════════════════════════════════════════════")
+	t.println(cyan, "  Plan Comparison")
+	t.println(cyan, "═══════════════════════════════════════════════════════")
+	t.printf(plain, "\n")
+
+	if !result.HasDifferences() {
+		t.println(green, "✓ No differences from the previous plan.")
+		return t.err
+	}
+
+	t.println(cyan, "Since the previous plan:")
+	t.println(cyan, "───────────────────────")
+	if len(result.Added) > 0 {
+		t.println(yellow, "  + %d new change(s)", len(result.Added))
+	}
+	if len(result.Resolved) > 0 {
+		t.println(green, "  ✓ %d resolved", len(result.Resolved))
+	}
+	if len(result.Changed) > 0 {
+		t.println(magenta, "  ~ %d planned differently", len(result.Changed))
+	}
+	if result.Unchanged > 0 {
+		t.println(white, "  • %d unchanged", result.Unchanged)
+	}
+
+	if len(result.Added) > 0 {
+		t.println(yellow, "\n+ New Changes (%d):", len(result.Added))
+		t.println(yellow, "────────────────────────────")
+		for _, c := range result.Added {
+			t.println(yellow, "  + %s (%s)", c.Address, actionLabel(c.Actions))
+			t.println(hiBlack, "    Type: %s", c.Type)
+		}
+	}
+
+	if len(result.Resolved) > 0 {
+		t.println(green, "\n✓ Resolved (%d):", len(result.Resolved))
+		t.println(green, "────────────────────────────")
+		for _, c := range result.Resolved {
+			t.println(green, "  ✓ %s (%s no longer planned)", c.Address, actionLabel(c.Actions))
+			t.println(hiBlack, "    Type: %s", c.Type)
+		}
+	}
+
+	if len(result.Changed) > 0 {
+		t.println(magenta, "\n~ Planned Differently (%d):", len(result.Changed))
+		t.println(magenta, "────────────────────────────")
+		for _, d := range result.Changed {
+			t.println(magenta, "  ~ %s", d.Address)
+			t.println(hiBlack, "    Type: %s", d.Type)
+			if d.ActionsChanged() {
+				t.println(hiYellow, "      action: %s → %s", actionLabel(d.OldActions), actionLabel(d.NewActions))
+			}
+			for _, a := range d.Attributes {
+				t.println(yellow, "      ~ %s: %s → %s", a.Name,
+					comparedValue(a.Old, a.OldUnknown, a.Sensitive), comparedValue(a.New, a.NewUnknown, a.Sensitive))
+			}
+		}
+	}
+
+	t.printf(plain, "\n")
+	return t.err
+}
+
+// FormatComparison writes the differences between two plans to w as
+// markdown
+func (f *MarkdownFormatter) FormatComparison(w io.Writer, result *compare.Result) error {
+	var sb strings.Builder
+
+	sb.WriteString("## 🔁 Changes Since the Previous Plan\n\n")
+
+	if !result.HasDifferences() {
+		sb.WriteString("✅ **No differences** from the previous plan.\n")
+	} else {
+		sb.WriteString("| | Count |\n")
+		sb.WriteString("|--------|-------|\n")
+		sb.WriteString(fmt.Sprintf("| 🆕 **New changes** | %d |\n", len(result.Added)))
+		sb.WriteString(fmt.Sprintf("| ✅ **Resolved** | %d |\n", len(result.Resolved)))
+		sb.WriteString(fmt.Sprintf("| 🔄 **Planned differently** | %d |\n", len(result.Changed)))
+		sb.WriteString(fmt.Sprintf("| ⚪ **Unchanged** | %d |\n", result.Unchanged))
+	}
+
+	if len(result.Added) > 0 {
+		sb.WriteString("\n### 🆕 New Changes\n\n")
+		sb.WriteString("```diff\n")
+		for _, c := range result.Added {
+			sb.WriteString(fmt.Sprintf("+ %s (%s)\n", c.Address, actionLabel(c.Actions)))
+		}
+		sb.WriteString("```\n")
+	}
+
+	if len(result.Resolved) > 0 {
+		sb.WriteString("\n### ✅ Resolved\n\n")
+		sb.WriteString("These changes are no longer planned:\n\n")
+		sb.WriteString("```diff\n")
+		for _, c := range result.Resolved {
+			sb.WriteString(fmt.Sprintf("- %s (%s)\n", c.Address, actionLabel(c.Actions)))
+		}
+		sb.WriteString("```\n")
+	}
+
+	if len(result.Changed) > 0 {
+		sb.WriteString("\n### 🔄 Planned Differently\n\n")
+		sb.WriteString("```diff\n")
+		for _, d := range result.Changed {
+			sb.WriteString(fmt.Sprintf("~ %s\n", d.Address))
+			if d.ActionsChanged() {
+				sb.WriteString(fmt.Sprintf("!  action: %s → %s\n", actionLabel(d.OldActions), actionLabel(d.NewActions)))
+			}
+			for _, a := range d.Attributes {
+				sb.WriteString(fmt.Sprintf("  ~ %s: %s → %s\n", a.Name,
+					comparedValue(a.Old, a.OldUnknown, a.Sensitive), comparedValue(a.New, a.NewUnknown, a.Sensitive)))
+			}
+		}
+		sb.WriteString("```\n")
+	}
+
+	sb.WriteString("\n---\n")
+	sb.WriteString("*Generated by [InfraSync](https://github.com/kvizadsaderah/infrasync)*\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// actionLabel names the actions of a change the way the summary does
+func actionLabel(actions []string) string {
+	if len(actions) == 2 && (actions[0] == "delete" && actions[1] == "create" || actions[0] == "create" && actions[1] == "delete") {
+		return "replace"
+	}
+	return strings.Join(actions, ", ")
+}
+
+func comparedValue(v interface{}, unknown, sensitive bool) string {
+	switch {
+	case sensitive:
+		return "(sensitive)"
+	case unknown:
+		return "(known after apply)"
+	}
+	return formatValue(v)
+}
@@ -0,0 +1,33 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/compare"
+)
+
+func TestFormatComparison_Golden(t *testing.T) {
+	changed := compare.Plans(loadPlan(t, "compare_old.json"), loadPlan(t, "compare_new.json"))
+	same := compare.Plans(loadPlan(t, "compare_old.json"), loadPlan(t, "compare_old.json"))
+
+	tests := []struct {
+		name   string
+		result *compare.Result
+		format func(*bytes.Buffer, *compare.Result) error
+	}{
+		{"compare_cli", changed, func(b *bytes.Buffer, r *compare.Result) error { return (&CLIFormatter{}).FormatComparison(b, r) }},
+		{"compare_cli_same", same, func(b *bytes.Buffer, r *compare.Result) error { return (&CLIFormatter{}).FormatComparison(b, r) }},
+		{"compare_markdown", changed, func(b *bytes.Buffer, r *compare.Result) error { return (&MarkdownFormatter{}).FormatComparison(b, r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.format(&buf, tt.result); err != nil {
+				t.Fatalf("FormatComparison failed: %v", err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
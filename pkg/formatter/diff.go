@@ -0,0 +1,233 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Strings longer than this are diffed line by line, as formatValue would
+// cut them off
+const maxInlineString = 60
+
+// Unchanged lines shown around the changed ones in a hunk
+const diffContext = 3
+
+// stringDiff is the diff of a string attribute too long or too structured
+// to be shown on one line. JSON and YAML documents are compared in a
+// canonical layout, so reformatting and reordered keys don't show up.
+type stringDiff struct {
+	Kind  string // JSON, YAML or text
+	Hunks []diffHunk
+}
+
+// diffHunk is a group of changed lines with some context, as in a unified
+// diff
+type diffHunk struct {
+	BeforeStart, BeforeLen int
+	AfterStart, AfterLen   int
+	Lines                  []diffLine
+}
+
+// Header returns the hunk header, e.g. `@@ -3,7 +3,8 @@`
+func (h diffHunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.BeforeStart, h.BeforeLen, h.AfterStart, h.AfterLen)
+}
+
+// diffString returns the diff of a changed attribute holding multiline,
+// long, JSON or YAML strings on both sides, and false for other changes
+func diffString(a attributeChange) (stringDiff, bool) {
+	if a.Op != "~" || a.Before.Sensitive || a.After.Sensitive ||
+		a.Before.State != parser.ValueKnown || a.After.State != parser.ValueKnown {
+		return stringDiff{}, false
+	}
+	before, ok := a.Before.Raw.(string)
+	if !ok {
+		return stringDiff{}, false
+	}
+	after, ok := a.After.Raw.(string)
+	if !ok {
+		return stringDiff{}, false
+	}
+
+	beforeKind, beforeLines := documentLines(before)
+	afterKind, afterLines := documentLines(after)
+	if beforeKind != afterKind {
+		beforeKind, beforeLines = "text", textLines(before)
+		afterLines = textLines(after)
+	}
+	if beforeKind == "text" && !isMultiline(before) && !isMultiline(after) &&
+		len(before) <= maxInlineString && len(after) <= maxInlineString {
+		return stringDiff{}, false
+	}
+
+	return stringDiff{Kind: beforeKind, Hunks: unifiedHunks(diffLines(beforeLines, afterLines), diffContext)}, true
+}
+
+// documentLines returns the lines of a string in a canonical layout when
+// it holds a JSON or YAML object or array, and as is otherwise
+func documentLines(s string) (string, []string) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var v interface{}
+		if json.Unmarshal([]byte(trimmed), &v) == nil {
+			data, err := json.MarshalIndent(v, "", "  ")
+			if err == nil {
+				return "JSON", textLines(string(data))
+			}
+		}
+	}
+
+	// Single lines and plain text parse as YAML scalars
+	if isMultiline(s) {
+		var v interface{}
+		if yaml.Unmarshal([]byte(s), &v) == nil && !isScalar(v) {
+			var buf bytes.Buffer
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if enc.Encode(v) == nil && enc.Close() == nil {
+				return "YAML", textLines(buf.String())
+			}
+		}
+	}
+
+	return "text", textLines(s)
+}
+
+func textLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLine is a line of a line-by-line diff: + added, - removed, or a
+// space when on both sides
+type diffLine struct {
+	op   string
+	text string
+}
+
+// Largest LCS table diffLines builds, in cells; longer changes are shown
+// as the removal of the old lines and the addition of the new ones
+const maxDiffCells = 1 << 20
+
+// diffLines compares two lists of lines using their longest common
+// subsequence. Unchanged lines at the start and end are skipped before
+// building the table, which is quadratic in the lines left.
+func diffLines(before, after []string) []diffLine {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(before)+len(after)-prefix-suffix)
+	for _, l := range before[:prefix] {
+		lines = append(lines, diffLine{" ", l})
+	}
+	lines = append(lines, diffMiddle(before[prefix:len(before)-suffix], after[prefix:len(after)-suffix])...)
+	for _, l := range before[len(before)-suffix:] {
+		lines = append(lines, diffLine{" ", l})
+	}
+	return lines
+}
+
+// diffMiddle diffs the lines between the common prefix and suffix
+func diffMiddle(before, after []string) []diffLine {
+	lines := make([]diffLine, 0, len(before)+len(after))
+	if (len(before)+1)*(len(after)+1) > maxDiffCells {
+		for _, l := range before {
+			lines = append(lines, diffLine{"-", l})
+		}
+		for _, l := range after {
+			lines = append(lines, diffLine{"+", l})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, diffLine{" ", before[i]})
+			i++
+			j++
+		case i < len(before) && (j == len(after) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{"-", before[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{"+", after[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// unifiedHunks groups the changed lines of a diff into hunks with up to
+// context unchanged lines around them. Changes closer than twice the
+// context share a hunk.
+func unifiedHunks(lines []diffLine, context int) []diffHunk {
+	beforeNo := make([]int, len(lines))
+	afterNo := make([]int, len(lines))
+	b, a := 1, 1
+	for i, l := range lines {
+		beforeNo[i], afterNo[i] = b, a
+		if l.op != "+" {
+			b++
+		}
+		if l.op != "-" {
+			a++
+		}
+	}
+
+	hunks := make([]diffHunk, 0)
+	for i := 0; i < len(lines); {
+		if lines[i].op == " " {
+			i++
+			continue
+		}
+
+		end := i
+		for j := i; j < len(lines) && j-end <= 2*context; j++ {
+			if lines[j].op != " " {
+				end = j
+			}
+		}
+		start, stop := max(0, i-context), min(len(lines), end+context+1)
+
+		h := diffHunk{BeforeStart: beforeNo[start], AfterStart: afterNo[start], Lines: lines[start:stop]}
+		for _, l := range h.Lines {
+			if l.op != "+" {
+				h.BeforeLen++
+			}
+			if l.op != "-" {
+				h.AfterLen++
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks
+}
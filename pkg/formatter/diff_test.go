@@ -0,0 +1,152 @@
+package formatter
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []string
+		want          []diffLine
+	}{
+		{"equal", []string{"a", "b"}, []string{"a", "b"}, []diffLine{{" ", "a"}, {" ", "b"}}},
+		{"added", nil, []string{"a"}, []diffLine{{"+", "a"}}},
+		{"removed", []string{"a"}, nil, []diffLine{{"-", "a"}}},
+		{
+			"changed line",
+			[]string{"a", "b", "c"},
+			[]string{"a", "x", "c"},
+			[]diffLine{{" ", "a"}, {"-", "b"}, {"+", "x"}, {" ", "c"}},
+		},
+		{
+			"inserted and removed",
+			[]string{"a", "b", "c"},
+			[]string{"b", "c", "d"},
+			[]diffLine{{"-", "a"}, {" ", "b"}, {" ", "c"}, {"+", "d"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines_Large(t *testing.T) {
+	before := make([]string, 20000)
+	for i := range before {
+		before[i] = fmt.Sprintf("line %d", i)
+	}
+	count := func(lines []diffLine) map[string]int {
+		ops := make(map[string]int)
+		for _, l := range lines {
+			ops[l.op]++
+		}
+		return ops
+	}
+	allocated := func(f func()) uint64 {
+		var start, end runtime.MemStats
+		runtime.ReadMemStats(&start)
+		f()
+		runtime.ReadMemStats(&end)
+		return end.TotalAlloc - start.TotalAlloc
+	}
+
+	// A single changed line is found exactly, whatever the length
+	after := slices.Clone(before)
+	after[10000] = "changed"
+	var lines []diffLine
+	if n := allocated(func() { lines = diffLines(before, after) }); n > 16<<20 {
+		t.Errorf("diffLines() allocated %d MB for one changed line", n>>20)
+	}
+	if ops := count(lines); ops["-"] != 1 || ops["+"] != 1 || ops[" "] != 19999 {
+		t.Errorf("diffLines() = %v, want one changed line", ops)
+	}
+
+	// Changes far apart leave too many lines between them for the table;
+	// they are shown as replaced as a whole
+	after = slices.Clone(before)
+	after[5] = "first"
+	after[19990] = "last"
+	if n := allocated(func() { lines = diffLines(before, after) }); n > 16<<20 {
+		t.Errorf("diffLines() allocated %d MB for distant changes", n>>20)
+	}
+	if ops := count(lines); ops["-"] != 19986 || ops["+"] != 19986 || ops[" "] != 14 {
+		t.Errorf("diffLines() = %v, want the lines between the changes replaced", ops)
+	}
+	if hunks := unifiedHunks(lines, 3); len(hunks) != 1 || hunks[0].Header() != "@@ -3,19992 +3,19992 @@" {
+		t.Errorf("unifiedHunks() = %d hunks, want one replacing the changed block", len(hunks))
+	}
+}
+
+func TestUnifiedHunks(t *testing.T) {
+	before := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}
+	after := []string{"1", "two", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13"}
+
+	hunks := unifiedHunks(diffLines(before, after), 2)
+	if len(hunks) != 2 {
+		t.Fatalf("unifiedHunks() returned %d hunks, want 2: %v", len(hunks), hunks)
+	}
+	if got, want := hunks[0].Header(), "@@ -1,4 +1,4 @@"; got != want {
+		t.Errorf("first hunk header = %q, want %q", got, want)
+	}
+	if got, want := hunks[1].Header(), "@@ -11,2 +11,3 @@"; got != want {
+		t.Errorf("second hunk header = %q, want %q", got, want)
+	}
+
+	// Changes closer than twice the context share a hunk
+	after = []string{"1", "two", "3", "4", "5", "six", "7", "8", "9", "10", "11", "12"}
+	if hunks := unifiedHunks(diffLines(before, after), 2); len(hunks) != 1 {
+		t.Errorf("unifiedHunks() returned %d hunks for nearby changes, want 1", len(hunks))
+	}
+
+	if hunks := unifiedHunks(diffLines(before, before), 2); len(hunks) != 0 {
+		t.Errorf("unifiedHunks() returned %d hunks for equal lines, want none", len(hunks))
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	known := func(v interface{}) parser.Value { return parser.Value{State: parser.ValueKnown, Raw: v} }
+	long := "arn:aws:iam::123456789012:role/service-role/deployment-pipeline-role"
+
+	tests := []struct {
+		name          string
+		change        attributeChange
+		wantOK        bool
+		wantKind      string
+		wantHunkCount int
+	}{
+		{"short string", attributeChange{Op: "~", Before: known("a"), After: known("b")}, false, "", 0},
+		{"number", attributeChange{Op: "~", Before: known(1.0), After: known(2.0)}, false, "", 0},
+		{"added", attributeChange{Op: "+", Before: parser.Value{}, After: known("a\nb")}, false, "", 0},
+		{"sensitive", attributeChange{Op: "~", Before: known("a\nb"), After: parser.Value{State: parser.ValueKnown, Raw: "a\nc", Sensitive: true}}, false, "", 0},
+		{"long string", attributeChange{Op: "~", Before: known(long + "-a"), After: known(long + "-b")}, true, "text", 1},
+		{"multiline", attributeChange{Op: "~", Before: known("a\nb\n"), After: known("a\nc\n")}, true, "text", 1},
+		{"json", attributeChange{Op: "~", Before: known(`{"a":1,"b":2}`), After: known(`{"b":3,"a":1}`)}, true, "JSON", 1},
+		{"json formatting", attributeChange{Op: "~", Before: known(`{"a":1,"b":2}`), After: known("{\n  \"b\": 2,\n  \"a\": 1\n}")}, true, "JSON", 0},
+		{"yaml", attributeChange{Op: "~", Before: known("a: 1\nb: 2\n"), After: known("b: 2\na: 3\n")}, true, "YAML", 1},
+		{"json and text", attributeChange{Op: "~", Before: known(`{"a":1}`), After: known("not\njson")}, true, "text", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := diffString(tt.change)
+			if ok != tt.wantOK {
+				t.Fatalf("diffString() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if d.Kind != tt.wantKind || len(d.Hunks) != tt.wantHunkCount {
+				t.Errorf("diffString() = %s with %d hunks, want %s with %d", d.Kind, len(d.Hunks), tt.wantKind, tt.wantHunkCount)
+			}
+		})
+	}
+}
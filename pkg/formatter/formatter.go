@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+	"github.com/mattn/go-isatty"
+)
+
+// Formatter renders a plan summary, together with the analysis results it
+// holds, to a writer
+type Formatter interface {
+	Format(w io.Writer, summary *parser.PlanSummary) error
+}
+
+// Analysis holds the analyzer results rendered around the plan summary
+type Analysis struct {
+	Score        *analyzer.PlanScore // added to the summary when set
+	PlanWarnings []analyzer.Warning  // plan-wide anomalies, shown first
+	Warnings     []analyzer.Warning
+	Moves        []analyzer.MoveSuggestion
+}
+
+// ColorMode controls whether terminal output is colored
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = iota // color terminals unless NO_COLOR is set
+	ColorAlways                  // always color
+	ColorNever                   // never color
+)
+
+// ParseColorMode parses auto, always or never
+func ParseColorMode(s string) (ColorMode, bool) {
+	switch s {
+	case "auto", "":
+		return ColorAuto, true
+	case "always":
+		return ColorAlways, true
+	case "never":
+		return ColorNever, true
+	}
+	return ColorAuto, false
+}
+
+// Enabled reports whether output to w should be colored. In auto mode only
+// terminals are colored, and not when NO_COLOR is set or TERM is dumb.
+func (m ColorMode) Enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// style is a set of terminal attributes
+type style []color.Attribute
+
+var (
+	plain        = style{}
+	bold         = style{color.Bold}
+	cyan         = style{color.FgCyan}
+	green        = style{color.FgGreen}
+	yellow       = style{color.FgYellow}
+	magenta      = style{color.FgMagenta}
+	red          = style{color.FgRed}
+	white        = style{color.FgWhite}
+	hiBlack      = style{color.FgHiBlack}
+	hiGreen      = style{color.FgHiGreen}
+	hiRed        = style{color.FgHiRed}
+	hiYellow     = style{color.FgHiYellow}
+	boldRed      = style{color.FgRed, color.Bold}
+	boldYellow   = style{color.FgYellow, color.Bold}
+	redBanner    = style{color.BgRed, color.FgWhite, color.Bold}
+	yellowBanner = style{color.BgYellow, color.FgBlack, color.Bold}
+)
+
+// terminal writes optionally colored text and remembers the first error
+type terminal struct {
+	w     io.Writer
+	color bool
+	err   error
+}
+
+func (t *terminal) paint(s style, text string) string {
+	if !t.color || len(s) == 0 {
+		return text
+	}
+	c := color.New(s...)
+	c.EnableColor()
+	return c.Sprint(text)
+}
+
+// printf writes styled text as is
+func (t *terminal) printf(s style, format string, a ...interface{}) {
+	if t.err != nil {
+		return
+	}
+	_, t.err = io.WriteString(t.w, t.paint(s, fmt.Sprintf(format, a...)))
+}
+
+// println writes a styled line
+func (t *terminal) println(s style, format string, a ...interface{}) {
+	t.printf(s, format, a...)
+	t.printf(plain, "\n")
+}
@@ -0,0 +1,127 @@
+package formatter
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// loadPlan parses a plan from testdata
+func loadPlan(t *testing.T, name string) *parser.PlanSummary {
+	t.Helper()
+	summary, err := parser.ParsePlanFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("ParsePlanFile failed: %v", err)
+	}
+	return summary
+}
+
+// testAnalysis returns analysis results covering every section
+func testAnalysis() Analysis {
+	return Analysis{
+		Score: &analyzer.PlanScore{
+			Total:    72,
+			Level:    analyzer.RiskHigh,
+			ByModule: map[string]float64{"": 60, "module.app": 12},
+		},
+		PlanWarnings: []analyzer.Warning{{
+			Rule:        "mass-deletion",
+			Level:       analyzer.RiskCritical,
+			Resource:    "plan",
+			Message:     "50% of existing resources will be DESTROYED (1 of 2)",
+			Explanation: "Mass deletions usually mean a wrong workspace, var file or backend configuration",
+		}},
+		Warnings: []analyzer.Warning{
+			{Rule: "database-deletion", Level: analyzer.RiskCritical, Resource: "aws_db_instance.prod", Message: "Database will be DELETED", Explanation: "Data loss is permanent"},
+			{Rule: "network-deletion", Level: analyzer.RiskHigh, Resource: "aws_security_group.web", Message: "Security group will be replaced", Explanation: "Dependent resources may lose connectivity"},
+			{Rule: "custom", Level: analyzer.RiskMedium, Resource: "module.app.aws_instance.web", Message: "Instance type changes"},
+		},
+		Moves: []analyzer.MoveSuggestion{
+			{From: "aws_s3_bucket.old", To: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Similarity: 0.9},
+		},
+	}
+}
+
+// assertGolden compares output with testdata/<name>.golden, rewriting the
+// file when the -update flag is set
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output differs from %s (run with -update to accept)\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+	}
+}
+
+func TestColorMode_Enabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	var buf bytes.Buffer
+	if !ColorAlways.Enabled(&buf) {
+		t.Error("ColorAlways.Enabled() = false, want true")
+	}
+	if ColorNever.Enabled(os.Stdout) {
+		t.Error("ColorNever.Enabled() = true, want false")
+	}
+	if ColorAuto.Enabled(&buf) {
+		t.Error("ColorAuto.Enabled(buffer) = true, want false")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if ColorAuto.Enabled(f) {
+		t.Error("ColorAuto.Enabled(regular file) = true, want false")
+	}
+}
+
+func TestColorMode_NoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	// NO_COLOR only affects auto mode
+	if ColorAuto.Enabled(os.Stdout) {
+		t.Error("ColorAuto.Enabled() with NO_COLOR = true, want false")
+	}
+	if !ColorAlways.Enabled(os.Stdout) {
+		t.Error("ColorAlways.Enabled() with NO_COLOR = false, want true")
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ColorMode
+		ok    bool
+	}{
+		{"auto", ColorAuto, true},
+		{"always", ColorAlways, true},
+		{"never", ColorNever, true},
+		{"sometimes", ColorAuto, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseColorMode(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseColorMode(%q) = %v, %v, want %v, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package formatter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// GroupBy selects how the changes of a plan are grouped
+type GroupBy string
+
+const (
+	GroupByAction   GroupBy = "action"
+	GroupByModule   GroupBy = "module"
+	GroupByProvider GroupBy = "provider"
+	GroupByType     GroupBy = "type"
+)
+
+// ParseGroupBy parses the value of the --group-by flag
+func ParseGroupBy(s string) (GroupBy, bool) {
+	switch g := GroupBy(s); g {
+	case GroupByAction, GroupByModule, GroupByProvider, GroupByType:
+		return g, true
+	}
+	return "", false
+}
+
+// title names the groups in headings
+func (g GroupBy) title() string {
+	switch g {
+	case GroupByModule:
+		return "Module"
+	case GroupByProvider:
+		return "Provider"
+	case GroupByType:
+		return "Resource Type"
+	}
+	return "Action"
+}
+
+// changeGroup holds the changes sharing a module, provider or type
+type changeGroup struct {
+	key     string
+	changes []parser.ResourceChange
+	counts  parser.PlanSummary
+}
+
+// groupChanges groups the changed resources, skipping no-ops, sorted by
+// key. The root module sorts first.
+func groupChanges(changes []parser.ResourceChange, by GroupBy) []*changeGroup {
+	byKey := make(map[string]*changeGroup)
+	groups := make([]*changeGroup, 0)
+	for _, c := range changes {
+		if c.IsNoOp {
+			continue
+		}
+
+		var key string
+		switch by {
+		case GroupByModule:
+			key = c.Module()
+		case GroupByProvider:
+			key = c.ProviderName
+		case GroupByType:
+			key = c.Type
+		}
+
+		g, ok := byKey[key]
+		if !ok {
+			g = &changeGroup{key: key}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.changes = append(g.changes, c)
+		countChange(&g.counts, c)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].key < groups[j].key })
+	return groups
+}
+
+// groupLabel names a group, with the root module and unknown providers
+// spelled out
+func groupLabel(by GroupBy, key string) string {
+	if by == GroupByModule {
+		return moduleLabel(key)
+	}
+	if key == "" {
+		return "(unknown)"
+	}
+	return key
+}
+
+func countChange(counts *parser.PlanSummary, c parser.ResourceChange) {
+	switch {
+	case c.IsCreate:
+		counts.ToCreate++
+	case c.IsUpdate:
+		counts.ToUpdate++
+	case c.IsReplace:
+		counts.ToReplace++
+	case c.IsDelete:
+		counts.ToDelete++
+	}
+}
+
+// changeSymbol returns the diff symbol and style of a change
+func changeSymbol(c parser.ResourceChange) (string, style) {
+	switch {
+	case c.IsCreate:
+		return "+", green
+	case c.IsUpdate:
+		return "~", yellow
+	case c.IsReplace:
+		return "⟳", magenta
+	case c.IsDelete:
+		return "-", red
+	}
+	return "•", white
+}
+
+// printCounts writes the non-zero action counts, e.g. "+2 ~1 -1"
+func (t *terminal) printCounts(counts parser.PlanSummary) {
+	parts := []struct {
+		format string
+		n      int
+		s      style
+	}{
+		{" +%d", counts.ToCreate, green},
+		{" ~%d", counts.ToUpdate, yellow},
+		{" ⟳%d", counts.ToReplace, magenta},
+		{" -%d", counts.ToDelete, red},
+	}
+	for _, p := range parts {
+		if p.n > 0 {
+			t.printf(p.s, p.format, p.n)
+		}
+	}
+	t.printf(plain, "\n")
+}
+
+// printChangesGrouped lists the changes by module, provider or type
+func (f *CLIFormatter) printChangesGrouped(t *terminal, summary *parser.PlanSummary) {
+	if f.GroupBy == GroupByModule {
+		f.printModuleTree(t, summary)
+		return
+	}
+
+	for _, g := range groupChanges(summary.Changes, f.GroupBy) {
+		t.printf(cyan, "\n%s (%d):", groupLabel(f.GroupBy, g.key), len(g.changes))
+		t.printCounts(g.counts)
+		t.println(cyan, "────────────────────────────")
+		for _, c := range g.changes {
+			symbol, s := changeSymbol(c)
+			t.println(s, "  %s %s", symbol, c.Address)
+			f.printChangeDetails(t, c, "    ")
+		}
+	}
+
+	t.printf(plain, "\n")
+}
+
+// printChangeDetails writes the attributes of a change in verbose mode
+func (f *CLIFormatter) printChangeDetails(t *terminal, c parser.ResourceChange, indent string) {
+	if !f.Verbose {
+		return
+	}
+	switch {
+	case c.IsCreate:
+		f.printAttributes(t, c, true, indent, hiGreen)
+	case c.IsDelete:
+		f.printAttributes(t, c, false, indent, hiRed)
+	default:
+		f.printAttributeDiff(t, c, indent)
+	}
+}
+
+// moduleNode is a module in the tree of a plan's changes. Counts include
+// the changes of nested modules.
+type moduleNode struct {
+	call     string
+	address  string
+	changes  []parser.ResourceChange
+	children []*moduleNode
+	counts   parser.PlanSummary
+}
+
+func (n *moduleNode) child(call string) *moduleNode {
+	for _, c := range n.children {
+		if c.call == call {
+			return c
+		}
+	}
+	address := call
+	if n.address != "" {
+		address = n.address + "." + call
+	}
+	c := &moduleNode{call: call, address: address}
+	n.children = append(n.children, c)
+	return c
+}
+
+func moduleTree(changes []parser.ResourceChange) *moduleNode {
+	root := &moduleNode{}
+	for _, c := range changes {
+		if c.IsNoOp {
+			continue
+		}
+		n := root
+		countChange(&n.counts, c)
+		for _, call := range parser.ModuleCalls(c.Module()) {
+			n = n.child(call)
+			countChange(&n.counts, c)
+		}
+		n.changes = append(n.changes, c)
+	}
+
+	var sortChildren func(n *moduleNode)
+	sortChildren = func(n *moduleNode) {
+		sort.Slice(n.children, func(i, j int) bool { return n.children[i].call < n.children[j].call })
+		for _, c := range n.children {
+			sortChildren(c)
+		}
+	}
+	sortChildren(root)
+
+	return root
+}
+
+// printModuleTree draws the module tree with the changes of each module
+// above its nested modules
+func (f *CLIFormatter) printModuleTree(t *terminal, summary *parser.PlanSummary) {
+	t.println(cyan, "\nChanges by Module:")
+	t.println(cyan, "──────────────────")
+
+	root := moduleTree(summary.Changes)
+	t.printf(plain, "%s", moduleLabel(""))
+	t.printCounts(root.counts)
+	f.printModuleNode(t, root, "")
+
+	t.printf(plain, "\n")
+}
+
+func (f *CLIFormatter) printModuleNode(t *terminal, n *moduleNode, prefix string) {
+	entries := len(n.changes) + len(n.children)
+	for i, c := range n.changes {
+		branch, next := treeBranch(i == entries-1)
+		symbol, s := changeSymbol(c)
+		t.printf(hiBlack, "%s%s", prefix, branch)
+		t.println(s, "%s %s", symbol, strings.TrimPrefix(c.Address, n.address+"."))
+		f.printChangeDetails(t, c, prefix+next)
+	}
+	for i, child := range n.children {
+		branch, next := treeBranch(len(n.changes)+i == entries-1)
+		t.printf(hiBlack, "%s%s", prefix, branch)
+		t.printf(plain, "%s", child.call)
+		t.printCounts(child.counts)
+		f.printModuleNode(t, child, prefix+next)
+	}
+}
+
+// treeBranch returns the branch drawn before an entry and the prefix of
+// the entries nested below it
+func treeBranch(last bool) (string, string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
@@ -0,0 +1,35 @@
+package formatter
+
+import (
+	"testing"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	for _, s := range []string{"action", "module", "provider", "type"} {
+		if g, ok := ParseGroupBy(s); !ok || string(g) != s {
+			t.Errorf("ParseGroupBy(%q) = %q, %v", s, g, ok)
+		}
+	}
+	if _, ok := ParseGroupBy("resource"); ok {
+		t.Error("Expected ParseGroupBy to reject an unknown grouping")
+	}
+}
+
+func TestModuleTree(t *testing.T) {
+	root := moduleTree(loadPlan(t, "modules.json").Changes)
+
+	if len(root.changes) != 1 || root.counts.ToUpdate != 2 || root.counts.ToCreate != 2 {
+		t.Errorf("Unexpected root module: %d changes, counts %+v", len(root.changes), root.counts)
+	}
+	if len(root.children) != 2 || root.children[0].call != "module.dns" || root.children[1].call != "module.vpc" {
+		t.Fatalf("Unexpected child modules: %+v", root.children)
+	}
+
+	vpc := root.children[1]
+	if vpc.counts.ToUpdate != 1 || vpc.counts.ToReplace != 1 || vpc.counts.ToCreate != 1 {
+		t.Errorf("Expected counts of nested modules in module.vpc, got %+v", vpc.counts)
+	}
+	if len(vpc.children) != 1 || vpc.children[0].address != `module.vpc.module.subnets["eu.west"]` {
+		t.Errorf("Unexpected nested module: %+v", vpc.children)
+	}
+}
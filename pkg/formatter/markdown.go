@@ -2,16 +2,28 @@ package formatter
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/kvizadsaderah/infrasync/pkg/parser"
 )
 
-// MarkdownFormatter formats plan output as GitHub-flavored markdown
+// MarkdownFormatter formats plan output as GitHub-flavored markdown with
+// the sections of DefaultTemplate
 type MarkdownFormatter struct {
 	ShowDetails   bool
 	CompactMode   bool
 	ShowUnchanged bool
+	Order         AttributeOrder
+	GroupBy       GroupBy
+
+	// MaxLength limits the length of the report, e.g. to a platform's
+	// CommentLimit; zero means no limit. See FormatParts.
+	MaxLength   int
+	Overflow    Overflow
+	ArtifactURL string // full report pointed to when truncating
+
+	Analysis
 }
 
 // NewMarkdownFormatter creates a new markdown formatter
@@ -23,188 +35,67 @@ func NewMarkdownFormatter(showDetails, compact, showUnchanged bool) *MarkdownFor
 	}
 }
 
-// Format outputs the plan summary as markdown
-func (f *MarkdownFormatter) Format(summary *parser.PlanSummary) string {
-	var sb strings.Builder
-
-	// Header
-	sb.WriteString("## 🔄 Terraform Plan Summary\n\n")
-
-	// Quick stats
-	f.writeStatistics(&sb, summary)
+// markdownTemplate renders the sections of the markdown report
+var markdownTemplate = DefaultTemplate()
 
-	// Warnings
-	if summary.ToDelete > 0 || summary.ToReplace > 0 {
-		sb.WriteString("\n")
-		sb.WriteString("### ⚠️ Warning: Destructive Changes Detected\n\n")
-		sb.WriteString("This plan includes destructive changes. Please review carefully:\n\n")
-		if summary.ToDelete > 0 {
-			sb.WriteString(fmt.Sprintf("- **%d resource(s) will be DESTROYED**\n", summary.ToDelete))
-		}
-		if summary.ToReplace > 0 {
-			sb.WriteString(fmt.Sprintf("- **%d resource(s) will be REPLACED** (destroyed and recreated)\n", summary.ToReplace))
-		}
-	}
-
-	// Changes by type
-	if !f.CompactMode {
-		f.writeChangesByType(&sb, summary)
+// Format writes the plan summary and analysis results to w as markdown.
+// Reports split to fit MaxLength are written one after the other, each
+// starting with a part marker.
+func (f *MarkdownFormatter) Format(w io.Writer, summary *parser.PlanSummary) error {
+	parts, err := f.FormatParts(summary)
+	if err != nil {
+		return err
 	}
-
-	// Footer
-	sb.WriteString("\n---\n")
-	sb.WriteString(fmt.Sprintf("*Generated by [InfraSync](https://github.com/kvizadsaderah/infrasync) • Terraform %s*\n", summary.TerraformVersion))
-
-	return sb.String()
+	_, err = io.WriteString(w, strings.Join(parts, ""))
+	return err
 }
 
-func (f *MarkdownFormatter) writeStatistics(sb *strings.Builder, summary *parser.PlanSummary) {
-	sb.WriteString("### 📊 Changes Overview\n\n")
-
-	if summary.ToCreate == 0 && summary.ToUpdate == 0 && summary.ToReplace == 0 && summary.ToDelete == 0 {
-		sb.WriteString("✅ **No changes.** Infrastructure is up-to-date.\n")
-		return
+// FormatParts renders the report as one or more markdown documents, each
+// no longer than MaxLength when set. A report that is too long lists the
+// summary and warnings first and leaves out attribute details, and is
+// then split into numbered parts or truncated, depending on Overflow.
+func (f *MarkdownFormatter) FormatParts(summary *parser.PlanSummary) ([]string, error) {
+	doc, err := f.render(summary, "markdown")
+	if err != nil || f.MaxLength <= 0 || len(doc) <= f.MaxLength {
+		return []string{doc}, err
 	}
 
-	sb.WriteString("| Action | Count |\n")
-	sb.WriteString("|--------|-------|\n")
-
-	if summary.ToCreate > 0 {
-		sb.WriteString(fmt.Sprintf("| ✅ **Create** | %d |\n", summary.ToCreate))
-	}
-	if summary.ToUpdate > 0 {
-		sb.WriteString(fmt.Sprintf("| 🔄 **Update** | %d |\n", summary.ToUpdate))
+	sf := *f
+	if doc, err = sf.render(summary, "prioritized"); err != nil || len(doc) <= f.MaxLength {
+		return []string{doc}, err
 	}
-	if summary.ToReplace > 0 {
-		sb.WriteString(fmt.Sprintf("| ⚠️ **Replace** | %d |\n", summary.ToReplace))
-	}
-	if summary.ToDelete > 0 {
-		sb.WriteString(fmt.Sprintf("| ❌ **Destroy** | %d |\n", summary.ToDelete))
+	sf.ShowDetails = false
+	if doc, err = sf.render(summary, "prioritized"); err != nil {
+		return nil, err
 	}
-	if summary.NoChanges > 0 && f.ShowUnchanged {
-		sb.WriteString(fmt.Sprintf("| ⚪ **No Change** | %d |\n", summary.NoChanges))
-	}
-
-	total := summary.ToCreate + summary.ToUpdate + summary.ToReplace + summary.ToDelete
-	sb.WriteString(fmt.Sprintf("| **Total** | **%d** |\n", total))
+	return sf.fit(doc, "\n<details>\n"), nil
 }
 
-func (f *MarkdownFormatter) writeChangesByType(sb *strings.Builder, summary *parser.PlanSummary) {
-	// Create
-	creates := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsCreate })
-	if len(creates) > 0 {
-		sb.WriteString("\n<details>\n")
-		sb.WriteString(fmt.Sprintf("<summary>✅ <b>Resources to CREATE (%d)</b></summary>\n\n", len(creates)))
-		sb.WriteString("```diff\n")
-		for _, c := range creates {
-			sb.WriteString(fmt.Sprintf("+ %s\n", c.Address))
-			if f.ShowDetails {
-				sb.WriteString(fmt.Sprintf("  Type: %s\n", c.Type))
-			}
-		}
-		sb.WriteString("```\n")
-		sb.WriteString("</details>\n")
-	}
-
-	// Update
-	updates := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsUpdate })
-	if len(updates) > 0 {
-		sb.WriteString("\n<details>\n")
-		sb.WriteString(fmt.Sprintf("<summary>🔄 <b>Resources to UPDATE (%d)</b></summary>\n\n", len(updates)))
-		sb.WriteString("```diff\n")
-		for _, c := range updates {
-			sb.WriteString(fmt.Sprintf("~ %s\n", c.Address))
-			if f.ShowDetails {
-				sb.WriteString(fmt.Sprintf("  Type: %s\n", c.Type))
-				f.writeAttributeDiffMarkdown(sb, c)
-			}
-		}
-		sb.WriteString("```\n")
-		sb.WriteString("</details>\n")
-	}
-
-	// Replace
-	replaces := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsReplace })
-	if len(replaces) > 0 {
-		sb.WriteString("\n<details>\n")
-		sb.WriteString(fmt.Sprintf("<summary>⚠️ <b>Resources to REPLACE (%d)</b></summary>\n\n", len(replaces)))
-		sb.WriteString("> **Warning:** These resources will be destroyed and recreated.\n\n")
-		sb.WriteString("```diff\n")
-		for _, c := range replaces {
-			sb.WriteString(fmt.Sprintf("!⟳ %s\n", c.Address))
-			if f.ShowDetails {
-				sb.WriteString(fmt.Sprintf("  Type: %s\n", c.Type))
-			}
-		}
-		sb.WriteString("```\n")
-		sb.WriteString("</details>\n")
-	}
-
-	// Delete
-	deletes := f.filterChanges(summary.Changes, func(c parser.ResourceChange) bool { return c.IsDelete })
-	if len(deletes) > 0 {
-		sb.WriteString("\n<details>\n")
-		sb.WriteString(fmt.Sprintf("<summary>❌ <b>Resources to DESTROY (%d)</b></summary>\n\n", len(deletes)))
-		sb.WriteString("> **Danger:** These resources will be permanently deleted.\n\n")
-		sb.WriteString("```diff\n")
-		for _, c := range deletes {
-			sb.WriteString(fmt.Sprintf("- %s\n", c.Address))
-			if f.ShowDetails {
-				sb.WriteString(fmt.Sprintf("  Type: %s\n", c.Type))
-			}
-		}
-		sb.WriteString("```\n")
-		sb.WriteString("</details>\n")
+// render executes sections of the default template with the view model
+// of the plan
+func (f *MarkdownFormatter) render(summary *parser.PlanSummary, sections ...string) (string, error) {
+	tf := &TemplateFormatter{
+		ShowDetails:   f.ShowDetails,
+		CompactMode:   f.CompactMode,
+		ShowUnchanged: f.ShowUnchanged,
+		Order:         f.Order,
+		GroupBy:       f.GroupBy,
+		Analysis:      f.Analysis,
 	}
-}
-
-func (f *MarkdownFormatter) writeAttributeDiffMarkdown(sb *strings.Builder, change parser.ResourceChange) {
-	before := change.Before
-	after := change.After
-
-	allKeys := make(map[string]bool)
-	for k := range before {
-		allKeys[k] = true
-	}
-	for k := range after {
-		allKeys[k] = true
-	}
-
-	count := 0
-	for key := range allKeys {
-		beforeVal, existsBefore := before[key]
-		afterVal, existsAfter := after[key]
-
-		if existsBefore && existsAfter {
-			beforeStr := formatValue(beforeVal)
-			afterStr := formatValue(afterVal)
-			if beforeStr != afterStr {
-				sb.WriteString(fmt.Sprintf("  ~ %s: %s → %s\n", key, beforeStr, afterStr))
-				count++
-			}
-		} else if existsAfter {
-			sb.WriteString(fmt.Sprintf("  + %s: %s\n", key, formatValue(afterVal)))
-			count++
-		} else if existsBefore {
-			sb.WriteString(fmt.Sprintf("  - %s: %s\n", key, formatValue(beforeVal)))
-			count++
-		}
-
-		if count >= 5 && !f.ShowDetails {
-			sb.WriteString("  ... (truncated)\n")
-			break
-		}
+	var sb strings.Builder
+	if err := executeSections(&sb, tf.Data(summary), sections...); err != nil {
+		return "", err
 	}
+	return sb.String(), nil
 }
 
-func (f *MarkdownFormatter) filterChanges(changes []parser.ResourceChange, predicate func(parser.ResourceChange) bool) []parser.ResourceChange {
-	result := make([]parser.ResourceChange, 0)
-	for _, c := range changes {
-		if predicate(c) {
-			result = append(result, c)
+func executeSections(sb *strings.Builder, data *TemplateData, sections ...string) error {
+	for _, name := range sections {
+		if err := markdownTemplate.ExecuteTemplate(sb, name, data); err != nil {
+			return fmt.Errorf("error executing template: %w", err)
 		}
 	}
-	return result
+	return nil
 }
 
 // FormatCompact provides a compact single-line summary for PR titles
@@ -0,0 +1,246 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Overflow selects what happens to a markdown report that is still too
+// long once attribute details are left out
+type Overflow string
+
+const (
+	OverflowSplit    Overflow = "split"    // split into numbered parts, the default
+	OverflowTruncate Overflow = "truncate" // cut, pointing to the full report
+)
+
+// ParseOverflow parses the value of the --overflow flag
+func ParseOverflow(s string) (Overflow, bool) {
+	switch o := Overflow(s); o {
+	case OverflowSplit, OverflowTruncate:
+		return o, true
+	case "":
+		return OverflowSplit, true
+	}
+	return "", false
+}
+
+// Longest comments accepted by code review platforms
+var commentLimits = map[string]int{
+	"github":       65536,
+	"gitlab":       1000000,
+	"azure-devops": 150000,
+	"bitbucket":    32768,
+}
+
+// CommentLimit returns the longest comment accepted by a code review
+// platform: github, gitlab, azure-devops or bitbucket
+func CommentLimit(platform string) (int, bool) {
+	limit, ok := commentLimits[platform]
+	return limit, ok
+}
+
+// Platforms returns the names accepted by CommentLimit
+func Platforms() []string {
+	names := make([]string, 0, len(commentLimits))
+	for name := range commentLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fit splits or truncates a report to MaxLength. Lengths are measured in
+// bytes, which is never less than the characters counted by platforms.
+// The document is cut into a head, sections starting at one of the
+// separators, and the footer; sections are only left out or moved to
+// later parts as a whole, unless a single one is too long.
+func (f *MarkdownFormatter) fit(doc string, separators ...string) []string {
+	if len(doc) <= f.MaxLength {
+		return []string{doc}
+	}
+
+	head, sections, footer := cutSections(doc, separators)
+	if f.Overflow == OverflowTruncate {
+		return []string{f.truncate(head, sections, footer)}
+	}
+	return f.split(head, sections, footer)
+}
+
+// cutSections cuts a document before each separator and before the footer
+func cutSections(doc string, separators []string) (string, []string, string) {
+	footer := ""
+	if i := strings.LastIndex(doc, "\n---\n"); i >= 0 {
+		doc, footer = doc[:i], doc[i:]
+	}
+
+	cuts := make([]int, 0)
+	for _, sep := range separators {
+		for i := 0; ; {
+			j := strings.Index(doc[i:], sep)
+			if j < 0 {
+				break
+			}
+			if i+j > 0 {
+				cuts = append(cuts, i+j)
+			}
+			i += j + len(sep)
+		}
+	}
+	sort.Ints(cuts)
+
+	sections := make([]string, 0, len(cuts))
+	start := len(doc)
+	if len(cuts) > 0 {
+		start = cuts[0]
+	}
+	for i, c := range cuts {
+		end := len(doc)
+		if i+1 < len(cuts) {
+			end = cuts[i+1]
+		}
+		if end > c {
+			sections = append(sections, doc[c:end])
+		}
+	}
+	return doc[:start], sections, footer
+}
+
+// split packs the head, sections and footer into numbered parts
+func (f *MarkdownFormatter) split(head string, sections []string, footer string) []string {
+	// Room for the part marker and heading, with part numbers of up to
+	// three digits
+	budget := f.MaxLength - len(partHeader(999, 999))
+
+	pieces := splitText(head, budget)
+	for _, s := range sections {
+		pieces = append(pieces, splitSection(s, budget)...)
+	}
+	pieces = append(pieces, splitText(footer, budget)...)
+
+	parts := make([]string, 0)
+	current := ""
+	for _, p := range pieces {
+		if len(current)+len(p) > budget && current != "" {
+			parts = append(parts, current)
+			current = ""
+		}
+		current += p
+	}
+	if current != "" {
+		parts = append(parts, current)
+	}
+
+	if len(parts) == 1 {
+		return parts
+	}
+	for i := range parts {
+		parts[i] = partHeader(i+1, len(parts)) + strings.TrimPrefix(parts[i], "\n")
+	}
+	return parts
+}
+
+// partHeader starts each part of a split report. The marker lets tools
+// post the parts as separate comments.
+func partHeader(part, parts int) string {
+	header := fmt.Sprintf("<!-- infrasync:part %d/%d -->\n", part, parts)
+	if part > 1 {
+		header += fmt.Sprintf("## 🔄 Terraform Plan Summary (part %d of %d)\n\n", part, parts)
+	}
+	return header
+}
+
+// truncate keeps the head and as many sections as fit, followed by a
+// notice pointing to the full report
+func (f *MarkdownFormatter) truncate(head string, sections []string, footer string) string {
+	notice := func(left int) string {
+		s := fmt.Sprintf("\n> ✂️ **Report truncated** to fit the %d character limit", f.MaxLength)
+		if left > 0 {
+			s += fmt.Sprintf(": %d section(s) left out", left)
+		}
+		if f.ArtifactURL != "" {
+			return s + fmt.Sprintf(". See the [full report](%s).\n", f.ArtifactURL)
+		}
+		return s + ". The full report is available from the pipeline that produced this comment.\n"
+	}
+
+	budget := f.MaxLength - len(notice(len(sections))) - len(footer)
+	var sb strings.Builder
+	sb.WriteString(splitText(head, budget)[0])
+
+	kept := 0
+	for _, s := range sections {
+		if sb.Len()+len(s) > budget {
+			break
+		}
+		sb.WriteString(s)
+		kept++
+	}
+
+	sb.WriteString(notice(len(sections) - kept))
+	if sb.Len()+len(footer) <= f.MaxLength {
+		sb.WriteString(footer)
+	}
+	return sb.String()
+}
+
+// splitSection splits a section longer than budget. Collapsible sections
+// with a code block are split into several, each with the code block
+// closed and reopened.
+func splitSection(s string, budget int) []string {
+	if len(s) <= budget {
+		return []string{s}
+	}
+
+	open := strings.Index(s, "\n```")
+	end := strings.LastIndex(s, "```\n</details>\n")
+	if !strings.Contains(s, "<details>") || open < 0 || end <= open {
+		return splitText(s, budget)
+	}
+	open += strings.Index(s[open+1:], "\n") + 2
+	prefix, body, suffix := s[:open], s[open:end], s[end:]
+
+	continued := strings.Replace(prefix, "</summary>", " (continued)</summary>", 1)
+	sections := make([]string, 0)
+	for i, chunk := range splitText(body, budget-len(continued)-len(suffix)) {
+		p := prefix
+		if i > 0 {
+			p = continued
+		}
+		sections = append(sections, p+chunk+suffix)
+	}
+	return sections
+}
+
+// splitText splits text into chunks of at most budget bytes at line
+// boundaries, cutting lines that are longer on their own
+func splitText(s string, budget int) []string {
+	budget = max(budget, utf8.UTFMax)
+	chunks := make([]string, 0)
+	current := ""
+	for _, line := range strings.SplitAfter(s, "\n") {
+		for len(line) > budget {
+			cut := budget
+			for !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if current != "" {
+				chunks = append(chunks, current)
+				current = ""
+			}
+			chunks = append(chunks, line[:cut])
+			line = line[cut:]
+		}
+		if len(current)+len(line) > budget {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		current += line
+	}
+	if current != "" || len(chunks) == 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
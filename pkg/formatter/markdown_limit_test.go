@@ -0,0 +1,191 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// largePlan returns a plan with n created and n updated resources
+func largePlan(n int) *parser.PlanSummary {
+	summary := &parser.PlanSummary{TerraformVersion: "1.9.0"}
+	for i := 0; i < n; i++ {
+		summary.Changes = append(summary.Changes, parser.ResourceChange{
+			Address:  fmt.Sprintf("aws_instance.web[%d]", i),
+			Type:     "aws_instance",
+			IsCreate: true,
+		}, parser.ResourceChange{
+			Address:  fmt.Sprintf("aws_security_group.app[%d]", i),
+			Type:     "aws_security_group",
+			IsUpdate: true,
+			Before:   map[string]interface{}{"description": "old"},
+			After:    map[string]interface{}{"description": "new"},
+		})
+		summary.ToCreate++
+		summary.ToUpdate++
+	}
+	return summary
+}
+
+func formatParts(t *testing.T, f *MarkdownFormatter, summary *parser.PlanSummary) []string {
+	t.Helper()
+	parts, err := f.FormatParts(summary)
+	if err != nil {
+		t.Fatalf("FormatParts failed: %v", err)
+	}
+	return parts
+}
+
+func TestMarkdownFormatter_FormatPartsWithinLimit(t *testing.T) {
+	summary := largePlan(3)
+	f := &MarkdownFormatter{ShowDetails: true, Analysis: testAnalysis()}
+	want := formatParts(t, f, summary)
+
+	f.MaxLength = len(want[0])
+	if got := formatParts(t, f, summary); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("FormatParts() changed a report within the limit")
+	}
+}
+
+func TestMarkdownFormatter_FormatPartsCollapsesDetails(t *testing.T) {
+	summary := largePlan(20)
+	f := &MarkdownFormatter{ShowDetails: true, Analysis: testAnalysis()}
+	full := formatParts(t, f, summary)[0]
+
+	collapsed := &MarkdownFormatter{Analysis: testAnalysis()}
+	prioritized, err := collapsed.render(summary, "prioritized")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	f.MaxLength = len(prioritized)
+	if f.MaxLength >= len(full) {
+		t.Fatal("expected details to make the report longer")
+	}
+
+	parts := formatParts(t, f, summary)
+	if len(parts) != 1 {
+		t.Fatalf("FormatParts() returned %d parts, want 1", len(parts))
+	}
+	if strings.Contains(parts[0], "Type: aws_instance") {
+		t.Error("expected attribute details to be left out")
+	}
+	if strings.Index(parts[0], "Security & Risk Analysis") > strings.Index(parts[0], "Resources to CREATE") {
+		t.Error("expected warnings ahead of the changes")
+	}
+}
+
+func TestMarkdownFormatter_FormatPartsSplit(t *testing.T) {
+	summary := largePlan(200)
+	f := &MarkdownFormatter{ShowDetails: true, MaxLength: 4000, Analysis: testAnalysis()}
+
+	parts := formatParts(t, f, summary)
+	if len(parts) < 3 {
+		t.Fatalf("FormatParts() returned %d parts, want several", len(parts))
+	}
+
+	for i, p := range parts {
+		if len(p) > f.MaxLength {
+			t.Errorf("part %d is %d bytes long, limit %d", i+1, len(p), f.MaxLength)
+		}
+		if marker := fmt.Sprintf("<!-- infrasync:part %d/%d -->\n", i+1, len(parts)); !strings.HasPrefix(p, marker) {
+			t.Errorf("part %d doesn't start with %q", i+1, marker)
+		}
+		if strings.Count(p, "```")%2 != 0 || strings.Count(p, "<details>") != strings.Count(p, "</details>") {
+			t.Errorf("part %d has unbalanced code blocks or details", i+1)
+		}
+	}
+
+	if !strings.Contains(parts[0], "Changes Overview") || !strings.Contains(parts[0], "Database will be DELETED") {
+		t.Error("expected the summary and warnings in the first part")
+	}
+	all := strings.Join(parts, "")
+	for _, c := range summary.Changes {
+		if !strings.Contains(all, c.Address+"\n") {
+			t.Errorf("%s is missing from the split report", c.Address)
+		}
+	}
+	if !strings.Contains(parts[len(parts)-1], "Generated by [InfraSync]") {
+		t.Error("expected the footer in the last part")
+	}
+}
+
+func TestMarkdownFormatter_FormatPartsTruncate(t *testing.T) {
+	summary := largePlan(200)
+	f := &MarkdownFormatter{
+		ShowDetails: true,
+		MaxLength:   4000,
+		Overflow:    OverflowTruncate,
+		ArtifactURL: "https://ci.example.com/artifacts/plan.md",
+		Analysis:    testAnalysis(),
+	}
+
+	parts := formatParts(t, f, summary)
+	if len(parts) != 1 {
+		t.Fatalf("FormatParts() returned %d parts, want 1", len(parts))
+	}
+	report := parts[0]
+	if len(report) > f.MaxLength {
+		t.Errorf("report is %d bytes long, limit %d", len(report), f.MaxLength)
+	}
+	for _, want := range []string{"Database will be DELETED", "Report truncated", "section(s) left out", f.ArtifactURL, "Generated by [InfraSync]"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("truncated report doesn't contain %q", want)
+		}
+	}
+}
+
+func TestMarkdownFormatter_FormatStacksLimit(t *testing.T) {
+	stacks := []Stack{
+		{Name: "network", Summary: largePlan(100)},
+		{Name: "apps", Summary: largePlan(100), Analysis: testAnalysis()},
+	}
+	f := &MarkdownFormatter{ShowDetails: true, MaxLength: 5000}
+
+	var sb strings.Builder
+	if err := f.FormatStacks(&sb, stacks); err != nil {
+		t.Fatalf("FormatStacks failed: %v", err)
+	}
+	parts := strings.Split(sb.String(), "<!-- infrasync:part ")[1:]
+	if len(parts) < 2 {
+		t.Fatalf("FormatStacks() wrote %d parts, want several", len(parts))
+	}
+	for i, p := range parts {
+		if len("<!-- infrasync:part ")+len(p) > f.MaxLength {
+			t.Errorf("part %d is too long", i+1)
+		}
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		budget int
+		want   []string
+	}{
+		{"fits", "a\nb\n", 10, []string{"a\nb\n"}},
+		{"lines", "aaa\nbbb\nccc\n", 8, []string{"aaa\nbbb\n", "ccc\n"}},
+		{"long line", "aaaaaaaaaa\n", 4, []string{"aaaa", "aaaa", "aa\n"}},
+		{"runes", "ééé", 4, []string{"éé", "é"}},
+		{"empty", "", 4, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.budget)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentLimit(t *testing.T) {
+	if limit, ok := CommentLimit("github"); !ok || limit != 65536 {
+		t.Errorf("CommentLimit(github) = %d, %v", limit, ok)
+	}
+	if _, ok := CommentLimit("myspace"); ok {
+		t.Error("CommentLimit(myspace) ok = true")
+	}
+}
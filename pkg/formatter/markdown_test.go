@@ -0,0 +1,70 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestMarkdownFormatter_Golden(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      string
+		formatter *MarkdownFormatter
+	}{
+		{"markdown_empty", "empty.json", NewMarkdownFormatter(true, false, false)},
+		{"markdown_analysis", "mixed.json", &MarkdownFormatter{ShowDetails: true, Analysis: testAnalysis()}},
+		{"markdown_compact", "mixed.json", &MarkdownFormatter{CompactMode: true}},
+		{"markdown_group_module", "modules.json", &MarkdownFormatter{ShowDetails: true, GroupBy: GroupByModule}},
+		{"markdown_group_type", "modules.json", &MarkdownFormatter{GroupBy: GroupByType}},
+		{"markdown_data_sources", "datasources.json", &MarkdownFormatter{ShowDetails: true}},
+		{"markdown_values", "values.json", &MarkdownFormatter{ShowDetails: true}},
+		{"markdown_strings", "strings.json", &MarkdownFormatter{ShowDetails: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.formatter.Format(&buf, loadPlan(t, tt.plan)); err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+func TestMarkdownFormatter_AttributeOrderGolden(t *testing.T) {
+	important := DefaultAttributeOrder()
+	important.ImportantFirst = true
+
+	tests := []struct {
+		name      string
+		formatter *MarkdownFormatter
+	}{
+		{"markdown_attributes_sorted", &MarkdownFormatter{ShowDetails: true}},
+		{"markdown_attributes_important", &MarkdownFormatter{ShowDetails: true, Order: important}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				var buf bytes.Buffer
+				if err := tt.formatter.Format(&buf, loadPlan(t, "attributes.json")); err != nil {
+					t.Fatalf("Format failed: %v", err)
+				}
+				assertGolden(t, tt.name, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestMarkdownFormatter_FilteredGolden(t *testing.T) {
+	summary := loadPlan(t, "modules.json").Filter(parser.Filter{Modules: []string{"module.vpc"}})
+
+	var buf bytes.Buffer
+	if err := (&MarkdownFormatter{}).Format(&buf, summary); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	assertGolden(t, "markdown_filtered", buf.Bytes())
+}
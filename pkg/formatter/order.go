@@ -0,0 +1,64 @@
+package formatter
+
+import (
+	"sort"
+)
+
+// AttributeOrder controls the order in which attributes are listed.
+// Attributes are sorted by name. With ImportantFirst, the attributes listed
+// for the resource type come first, in the listed order, followed by the
+// ones listed under "*".
+type AttributeOrder struct {
+	ImportantFirst bool                `yaml:"important_first"`
+	Important      map[string][]string `yaml:"important"` // by resource type
+}
+
+// DefaultAttributeOrder returns sorted ordering, with important attributes
+// for common resource types ready to be enabled
+func DefaultAttributeOrder() AttributeOrder {
+	return AttributeOrder{
+		Important: map[string][]string{
+			"*":                       {"name", "tags"},
+			"aws_instance":            {"instance_type", "ami"},
+			"aws_db_instance":         {"engine", "engine_version", "instance_class", "allocated_storage", "deletion_protection"},
+			"aws_security_group":      {"ingress", "egress"},
+			"aws_s3_bucket":           {"bucket"},
+			"aws_lambda_function":     {"runtime", "handler", "memory_size", "timeout"},
+			"google_compute_instance": {"machine_type", "boot_disk"},
+			"azurerm_virtual_machine": {"vm_size"},
+		},
+	}
+}
+
+// keys returns the union of the keys of the given maps in display order
+func (o AttributeOrder) keys(resourceType string, maps ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	if !o.ImportantFirst {
+		return keys
+	}
+
+	ordered := make([]string, 0, len(keys))
+	for _, k := range append(append([]string{}, o.Important[resourceType]...), o.Important["*"]...) {
+		if seen[k] {
+			ordered = append(ordered, k)
+			seen[k] = false
+		}
+	}
+	for _, k := range keys {
+		if seen[k] {
+			ordered = append(ordered, k)
+		}
+	}
+	return ordered
+}
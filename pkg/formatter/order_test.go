@@ -0,0 +1,35 @@
+package formatter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttributeOrder_Keys(t *testing.T) {
+	before := map[string]interface{}{"tags": nil, "ami": nil, "name": nil}
+	after := map[string]interface{}{"instance_type": nil, "ami": nil, "zone": nil}
+	important := map[string][]string{
+		"aws_instance": {"instance_type", "missing", "ami"},
+		"*":            {"name", "ami"},
+	}
+
+	tests := []struct {
+		name         string
+		order        AttributeOrder
+		resourceType string
+		want         []string
+	}{
+		{"sorted", AttributeOrder{Important: important}, "aws_instance", []string{"ami", "instance_type", "name", "tags", "zone"}},
+		{"important first", AttributeOrder{ImportantFirst: true, Important: important}, "aws_instance", []string{"instance_type", "ami", "name", "tags", "zone"}},
+		{"wildcard only", AttributeOrder{ImportantFirst: true, Important: important}, "aws_s3_bucket", []string{"name", "ami", "instance_type", "tags", "zone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.order.keys(tt.resourceType, before, after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package formatter
+
+import (
+	"sort"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+)
+
+type moduleScore struct {
+	Module string
+	Score  float64
+}
+
+// sortedModuleScores returns the module scores highest first, skipping
+// modules without risky changes
+func sortedModuleScores(score *analyzer.PlanScore) []moduleScore {
+	modules := make([]moduleScore, 0, len(score.ByModule))
+	for m, s := range score.ByModule {
+		if s > 0 {
+			modules = append(modules, moduleScore{Module: m, Score: s})
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if modules[i].Score != modules[j].Score {
+			return modules[i].Score > modules[j].Score
+		}
+		return modules[i].Module < modules[j].Module
+	})
+	return modules
+}
+
+func moduleLabel(module string) string {
+	if module == "" {
+		return "(root)"
+	}
+	return module
+}
@@ -0,0 +1,167 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// Block Kit limits: Slack rejects messages breaking them
+const (
+	slackMaxText       = 3000 // characters of a section text
+	slackMaxListBlocks = 10   // sections per list, well below the 50 blocks of a message
+)
+
+// SlackFormatter formats plan output as a Slack Block Kit message: a
+// header, the counts and risk score, the top warnings and the destructive
+// changes in an attachment, which Slack collapses when long
+type SlackFormatter struct {
+	ArtifactURL string // full report linked from the message
+	Analysis
+}
+
+// Slack Block Kit payload
+type slackMessage struct {
+	Text        string            `json:"text"` // notification fallback
+	Blocks      []slackBlock      `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type  string `json:"type"` // plain_text or mrkdwn
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// Format writes the plan summary and analysis results to w as a Block Kit
+// JSON payload, ready to be posted to a Slack webhook
+func (f *SlackFormatter) Format(w io.Writer, summary *parser.PlanSummary) error {
+	return f.FormatStacks(w, []Stack{{Summary: summary, Analysis: f.Analysis}})
+}
+
+// FormatStacks writes one message for several plans, with the counts of
+// each stack. The formatter's own analysis results are ignored.
+func (f *SlackFormatter) FormatStacks(w io.Writer, stacks []Stack) error {
+	return writeJSON(w, f.message(newChatMessage(stacks)))
+}
+
+func (f *SlackFormatter) message(m *chatMessage) slackMessage {
+	msg := slackMessage{Text: m.headline()}
+	msg.Blocks = append(msg.Blocks, slackBlock{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: "🔄 Terraform Plan Summary", Emoji: true},
+	})
+
+	// Counts and risk score
+	fields := make([]slackText, 0)
+	for _, c := range []struct {
+		label string
+		n     int
+	}{
+		{"✅ Create", m.counts.ToCreate},
+		{"🔄 Update", m.counts.ToUpdate},
+		{"⚠️ Replace", m.counts.ToReplace},
+		{"❌ Destroy", m.counts.ToDelete},
+		{"📖 Read", m.counts.ToRead},
+	} {
+		if c.n > 0 {
+			fields = append(fields, slackMrkdwn(fmt.Sprintf("*%s*\n%d", c.label, c.n)))
+		}
+	}
+	if len(fields) == 0 {
+		msg.Blocks = append(msg.Blocks, slackSection("✅ *No changes.* Infrastructure is up-to-date."))
+	}
+	if m.score != nil {
+		fields = append(fields, slackMrkdwn("*🎯 Risk Score*\n"+scoreText(m.score)))
+	}
+	if len(fields) > 0 {
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Fields: fields})
+	}
+
+	// Stacks
+	if len(m.stacks) > 0 {
+		lines := make([]string, 0, len(m.stacks))
+		for _, s := range m.stacks {
+			line := fmt.Sprintf("*%s* %s", slackEscape(s.name), countsText(s.counts))
+			if s.score != nil {
+				line += " • risk " + scoreText(s.score)
+			}
+			lines = append(lines, line)
+		}
+		more := func(left int) string { return fmt.Sprintf("_…and %d more %s_", left, pluralize(left, "stack")) }
+		for _, text := range packLines(lines, slackMaxText, slackMaxListBlocks, more) {
+			msg.Blocks = append(msg.Blocks, slackSection(text))
+		}
+	}
+
+	// Top warnings
+	if top, left := m.topWarnings(); len(top) > 0 {
+		text := "*🔍 Top Warnings*"
+		for _, w := range top {
+			text += "\n" + slackWarning(w)
+		}
+		if left > 0 {
+			text += fmt.Sprintf("\n_…and %d more %s_", left, pluralize(left, "warning"))
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "divider"}, slackSection(clip(text, slackMaxText)))
+	}
+
+	footer := m.footer()
+	if f.ArtifactURL != "" {
+		footer += fmt.Sprintf(" • <%s|Full report>", f.ArtifactURL)
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{Type: "context", Elements: []slackText{slackMrkdwn(footer)}})
+
+	// Destructive changes, collapsed by Slack beyond a few lines
+	if len(m.destructive) > 0 {
+		blocks := []slackBlock{slackSection(fmt.Sprintf("*⚠️ Destructive Changes (%d)*", len(m.destructive)))}
+		lines := make([]string, 0, len(m.destructive))
+		for _, d := range m.destructive {
+			lines = append(lines, slackEscape(d))
+		}
+		fence := len("```\n\n```")
+		more := func(left int) string { return fmt.Sprintf("…and %d more", left) }
+		for _, text := range packLines(lines, slackMaxText-fence, slackMaxListBlocks, more) {
+			blocks = append(blocks, slackSection("```\n"+text+"\n```"))
+		}
+		msg.Attachments = append(msg.Attachments, slackAttachment{Color: "#d73a49", Blocks: blocks})
+	}
+
+	return msg
+}
+
+// slackWarning renders a warning as a line of mrkdwn
+func slackWarning(w analyzer.Warning) string {
+	line := fmt.Sprintf("%s *%s*", levelIcon(w.Level), slackEscape(w.Message))
+	if w.Resource != "" {
+		line += fmt.Sprintf(" · `%s`", slackEscape(w.Resource))
+	}
+	return line
+}
+
+func slackSection(text string) slackBlock {
+	t := slackMrkdwn(text)
+	return slackBlock{Type: "section", Text: &t}
+}
+
+func slackMrkdwn(text string) slackText {
+	return slackText{Type: "mrkdwn", Text: text}
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
@@ -0,0 +1,249 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// Stack is one plan of a report covering several Terraform stacks
+type Stack struct {
+	Name    string
+	Summary *parser.PlanSummary
+	Analysis
+}
+
+// FormatStacks writes a combined summary table followed by a section per
+// stack. The formatter's own analysis results are ignored.
+func (f *CLIFormatter) FormatStacks(w io.Writer, stacks []Stack) error {
+	t := &terminal{w: w, color: f.Color.Enabled(w)}
+
+	t.printf(plain, "\n")
+	t.println(cyan, "═══════════════════════════════════════════════════════")
+	t.println(cyan, "  Terraform Plan Summary (%d stacks)", len(stacks))
+	t.println(cyan, "═══════════════════════════════════════════════════════")
+	t.printf(plain, "\n")
+
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Stack\tCreate\tUpdate\tReplace\tDestroy\tScore\tCritical\tHigh\t")
+	var total parser.PlanSummary
+	for _, s := range stacks {
+		addCounts(&total, s.Summary)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%d\t%d\t\n", s.Name,
+			s.Summary.ToCreate, s.Summary.ToUpdate, s.Summary.ToReplace, s.Summary.ToDelete, stackScore(s),
+			countLevel(s.Analysis, analyzer.RiskCritical), countLevel(s.Analysis, analyzer.RiskHigh))
+	}
+	fmt.Fprintf(tw, "Total\t%d\t%d\t%d\t%d\t\t%d\t%d\t\n",
+		total.ToCreate, total.ToUpdate, total.ToReplace, total.ToDelete,
+		countLevelAll(stacks, analyzer.RiskCritical), countLevelAll(stacks, analyzer.RiskHigh))
+	tw.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " ")
+		switch i {
+		case 0:
+			t.println(cyan, "  %s", line)
+		case len(lines) - 1:
+			t.println(plain, "  %s", line)
+		default:
+			t.println(stackStyle(stacks[i-1]), "  %s", line)
+		}
+	}
+
+	for _, s := range stacks {
+		sf := *f
+		sf.Analysis = s.Analysis
+		sf.printPlanBanner(t)
+		sf.printSummary(t, s.Summary, "Stack: "+s.Name)
+		sf.printWarnings(t)
+		sf.printMovedBlocks(t)
+		sf.printDestructiveWarning(t, s.Summary)
+	}
+
+	return t.err
+}
+
+// FormatStacks writes the plan of each stack under its name. The
+// formatter's own analysis results are ignored.
+func (f *TerraformFormatter) FormatStacks(w io.Writer, stacks []Stack) error {
+	for _, s := range stacks {
+		t := &terminal{w: w, color: f.Color.Enabled(w)}
+		t.printf(plain, "\n")
+		t.println(cyan, "═══════════════════════════════════════════════════════")
+		t.println(cyan, "  Stack: %s", s.Name)
+		t.println(cyan, "═══════════════════════════════════════════════════════")
+		if t.err != nil {
+			return t.err
+		}
+
+		sf := *f
+		sf.Analysis = s.Analysis
+		if err := sf.Format(w, s.Summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatStacks writes a combined summary table followed by a section per
+// stack as markdown. The formatter's own analysis results are ignored.
+// Reports longer than MaxLength are fitted as described for FormatParts.
+func (f *MarkdownFormatter) FormatStacks(w io.Writer, stacks []Stack) error {
+	doc, err := f.renderStacks(stacks)
+	if err != nil {
+		return err
+	}
+	if f.MaxLength > 0 && len(doc) > f.MaxLength {
+		sf := *f
+		sf.ShowDetails = false
+		if doc, err = sf.renderStacks(stacks); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, strings.Join(sf.fit(doc, "\n## 📦 Stack: ", "\n<details>\n"), ""))
+		return err
+	}
+
+	_, err = io.WriteString(w, doc)
+	return err
+}
+
+func (f *MarkdownFormatter) renderStacks(stacks []Stack) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("## 🔄 Terraform Plan Summary (%d stacks)\n\n", len(stacks)))
+
+	withScore := false
+	for _, s := range stacks {
+		withScore = withScore || s.Score != nil
+	}
+
+	sb.WriteString("| Stack | Create | Update | Replace | Destroy |")
+	if withScore {
+		sb.WriteString(" Risk Score |")
+	}
+	sb.WriteString(" Warnings |\n")
+	sb.WriteString("|-------|-------:|-------:|--------:|--------:|")
+	if withScore {
+		sb.WriteString("------------|")
+	}
+	sb.WriteString("----------|\n")
+
+	var total parser.PlanSummary
+	for _, s := range stacks {
+		addCounts(&total, s.Summary)
+		sb.WriteString(fmt.Sprintf("| [`%s`](#-stack-%s) | %d | %d | %d | %d |", s.Name, anchor(s.Name),
+			s.Summary.ToCreate, s.Summary.ToUpdate, s.Summary.ToReplace, s.Summary.ToDelete))
+		if withScore {
+			sb.WriteString(fmt.Sprintf(" %s |", stackScore(s)))
+		}
+		sb.WriteString(fmt.Sprintf(" %s |\n", warningBadges(countLevel(s.Analysis, analyzer.RiskCritical), countLevel(s.Analysis, analyzer.RiskHigh))))
+	}
+	sb.WriteString(fmt.Sprintf("| **Total** | **%d** | **%d** | **%d** | **%d** |", total.ToCreate, total.ToUpdate, total.ToReplace, total.ToDelete))
+	if withScore {
+		sb.WriteString(" |")
+	}
+	sb.WriteString(fmt.Sprintf(" %s |\n", warningBadges(countLevelAll(stacks, analyzer.RiskCritical), countLevelAll(stacks, analyzer.RiskHigh))))
+
+	versions := make(map[string]bool)
+	for _, s := range stacks {
+		sf := *f
+		sf.Analysis = s.Analysis
+		versions[s.Summary.TerraformVersion] = true
+
+		sb.WriteString(fmt.Sprintf("\n## 📦 Stack: %s\n\n", s.Name))
+		doc, err := sf.render(s.Summary, "banner", "overview", "changes", "analysis")
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", s.Name, err)
+		}
+		sb.WriteString(doc)
+	}
+
+	footer := &TemplateData{Meta: TemplateMeta{TerraformVersion: strings.Join(sortedSet(versions), ", ")}}
+	if err := executeSections(&sb, footer, "footer"); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func addCounts(total, summary *parser.PlanSummary) {
+	total.ToCreate += summary.ToCreate
+	total.ToUpdate += summary.ToUpdate
+	total.ToReplace += summary.ToReplace
+	total.ToDelete += summary.ToDelete
+}
+
+func stackScore(s Stack) string {
+	if s.Score == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f (%s)", s.Score.Total, s.Score.Level)
+}
+
+// stackStyle colors a stack row by its most severe warning
+func stackStyle(s Stack) style {
+	switch {
+	case countLevel(s.Analysis, analyzer.RiskCritical) > 0:
+		return red
+	case countLevel(s.Analysis, analyzer.RiskHigh) > 0:
+		return yellow
+	}
+	return plain
+}
+
+func countLevel(a Analysis, level analyzer.RiskLevel) int {
+	return len(filterWarnings(a.PlanWarnings, level)) + len(filterWarnings(a.Warnings, level))
+}
+
+func countLevelAll(stacks []Stack, level analyzer.RiskLevel) int {
+	n := 0
+	for _, s := range stacks {
+		n += countLevel(s.Analysis, level)
+	}
+	return n
+}
+
+func warningBadges(criticals, highs int) string {
+	parts := make([]string, 0, 2)
+	if criticals > 0 {
+		parts = append(parts, fmt.Sprintf("🚨 %d", criticals))
+	}
+	if highs > 0 {
+		parts = append(parts, fmt.Sprintf("⚠️ %d", highs))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+// anchor returns the GitHub heading anchor suffix for a stack name
+func anchor(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
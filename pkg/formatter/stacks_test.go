@@ -0,0 +1,51 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testStacks(t *testing.T) []Stack {
+	return []Stack{
+		{Name: "data", Summary: loadPlan(t, "mixed.json"), Analysis: testAnalysis()},
+		{Name: "network", Summary: loadPlan(t, "attributes.json")},
+		{Name: "apps", Summary: loadPlan(t, "empty.json")},
+	}
+}
+
+func TestFormatStacks_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		format func(*bytes.Buffer, []Stack) error
+	}{
+		{"stacks_cli", func(b *bytes.Buffer, s []Stack) error { return (&CLIFormatter{}).FormatStacks(b, s) }},
+		{"stacks_markdown", func(b *bytes.Buffer, s []Stack) error {
+			return (&MarkdownFormatter{CompactMode: true}).FormatStacks(b, s)
+		}},
+		{"stacks_terraform", func(b *bytes.Buffer, s []Stack) error { return (&TerraformFormatter{}).FormatStacks(b, s) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.format(&buf, testStacks(t)); err != nil {
+				t.Fatalf("FormatStacks failed: %v", err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	tests := map[string]string{
+		"network":       "network",
+		"prod/app":      "prodapp",
+		"Data Platform": "data-platform",
+		"eu-west_1":     "eu-west_1",
+	}
+	for name, want := range tests {
+		if got := anchor(name); got != want {
+			t.Errorf("anchor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
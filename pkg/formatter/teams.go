@@ -0,0 +1,241 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// Teams rejects messages above 28 KB
+const (
+	teamsMaxBytes  = 28000
+	teamsMaxStacks = 50  // facts in the stacks list
+	teamsMaxText   = 500 // characters of a name, warning message or change
+)
+
+// TeamsFormatter formats plan output as a Microsoft Teams message with an
+// Adaptive Card: the counts and risk score, the top warnings and the
+// destructive changes, collapsed behind a toggle
+type TeamsFormatter struct {
+	ArtifactURL string // full report linked from the card
+	Analysis
+}
+
+// Teams message payload with an Adaptive Card attachment
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string            `json:"$schema"`
+	Type    string            `json:"type"`
+	Version string            `json:"version"`
+	Body    []teamsElement    `json:"body"`
+	Actions []teamsAction     `json:"actions,omitempty"`
+	MSTeams map[string]string `json:"msteams"`
+}
+
+type teamsElement struct {
+	Type      string         `json:"type"`
+	ID        string         `json:"id,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Size      string         `json:"size,omitempty"`
+	Weight    string         `json:"weight,omitempty"`
+	Color     string         `json:"color,omitempty"`
+	FontType  string         `json:"fontType,omitempty"`
+	IsSubtle  bool           `json:"isSubtle,omitempty"`
+	IsVisible *bool          `json:"isVisible,omitempty"`
+	Spacing   string         `json:"spacing,omitempty"`
+	Wrap      bool           `json:"wrap,omitempty"`
+	Separator bool           `json:"separator,omitempty"`
+	Facts     []teamsFact    `json:"facts,omitempty"`
+	Items     []teamsElement `json:"items,omitempty"`
+	Actions   []teamsAction  `json:"actions,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type           string   `json:"type"`
+	Title          string   `json:"title"`
+	URL            string   `json:"url,omitempty"`
+	TargetElements []string `json:"targetElements,omitempty"`
+}
+
+// Format writes the plan summary and analysis results to w as a Teams
+// message JSON payload, ready to be posted to a Teams webhook
+func (f *TeamsFormatter) Format(w io.Writer, summary *parser.PlanSummary) error {
+	return f.FormatStacks(w, []Stack{{Summary: summary, Analysis: f.Analysis}})
+}
+
+// FormatStacks writes one message for several plans, with the counts of
+// each stack. The formatter's own analysis results are ignored.
+func (f *TeamsFormatter) FormatStacks(w io.Writer, stacks []Stack) error {
+	m := newChatMessage(stacks)
+	top, _ := m.topWarnings()
+	l := teamsLimits{
+		stacks:      min(len(m.stacks), teamsMaxStacks),
+		warnings:    len(top),
+		destructive: len(m.destructive),
+	}
+
+	// Leave out as few destructive changes as needed to fit the size limit,
+	// then stacks, then warnings
+	for _, n := range []*int{&l.destructive, &l.stacks, &l.warnings} {
+		if fitsTeams(f.message(m, l)) {
+			break
+		}
+		keep := *n
+		dropped := sort.Search(keep+1, func(d int) bool {
+			*n = keep - d
+			return fitsTeams(f.message(m, l))
+		})
+		*n = max(keep-dropped, 0)
+	}
+	return writeJSON(w, f.message(m, l))
+}
+
+// teamsLimits is the number of stacks, warnings and destructive changes
+// listed in a card; the others are counted
+type teamsLimits struct {
+	stacks, warnings, destructive int
+}
+
+// fitsTeams measures the indented payload, which is longer than the one
+// written, as HTML characters are escaped
+func fitsTeams(msg teamsMessage) bool {
+	data, err := json.MarshalIndent(msg, "", "  ")
+	return err == nil && len(data) <= teamsMaxBytes
+}
+
+// message builds the card listing the first stacks, warnings and
+// destructive changes allowed by l
+func (f *TeamsFormatter) message(m *chatMessage, l teamsLimits) teamsMessage {
+	body := []teamsElement{{Type: "TextBlock", Text: "🔄 Terraform Plan Summary", Size: "Large", Weight: "Bolder", Wrap: true}}
+
+	// Counts and risk score
+	facts := make([]teamsFact, 0)
+	for _, c := range []struct {
+		label string
+		n     int
+	}{
+		{"✅ Create", m.counts.ToCreate},
+		{"🔄 Update", m.counts.ToUpdate},
+		{"⚠️ Replace", m.counts.ToReplace},
+		{"❌ Destroy", m.counts.ToDelete},
+		{"📖 Read", m.counts.ToRead},
+	} {
+		if c.n > 0 {
+			facts = append(facts, teamsFact{Title: c.label, Value: fmt.Sprint(c.n)})
+		}
+	}
+	if len(facts) == 0 {
+		body = append(body, teamsElement{Type: "TextBlock", Text: "✅ **No changes.** Infrastructure is up-to-date.", Wrap: true})
+	}
+	if m.score != nil {
+		facts = append(facts, teamsFact{Title: "🎯 Risk Score", Value: scoreText(m.score)})
+	}
+	if len(facts) > 0 {
+		body = append(body, teamsElement{Type: "FactSet", Facts: facts})
+	}
+
+	// Stacks
+	if len(m.stacks) > 0 {
+		body = append(body, teamsElement{Type: "TextBlock", Text: "📦 Stacks", Weight: "Bolder", Wrap: true, Separator: true})
+		facts := make([]teamsFact, 0, l.stacks)
+		for _, s := range m.stacks[:l.stacks] {
+			value := countsText(s.counts)
+			if s.score != nil {
+				value += " • risk " + scoreText(s.score)
+			}
+			facts = append(facts, teamsFact{Title: clip(s.name, teamsMaxText), Value: value})
+		}
+		if len(facts) > 0 {
+			body = append(body, teamsElement{Type: "FactSet", Facts: facts})
+		}
+		if left := len(m.stacks) - l.stacks; left > 0 {
+			body = append(body, teamsElement{Type: "TextBlock", Text: fmt.Sprintf("…and %d more %s", left, pluralize(left, "stack")), IsSubtle: true, Wrap: true})
+		}
+	}
+
+	// Top warnings
+	if top, left := m.topWarnings(); len(top) > 0 {
+		body = append(body, teamsElement{Type: "TextBlock", Text: "🔍 Top Warnings", Weight: "Bolder", Wrap: true, Separator: true})
+		for _, w := range top[:l.warnings] {
+			body = append(body, teamsWarning(w))
+		}
+		if left += len(top) - l.warnings; left > 0 {
+			body = append(body, teamsElement{Type: "TextBlock", Text: fmt.Sprintf("…and %d more %s", left, pluralize(left, "warning")), IsSubtle: true, Wrap: true})
+		}
+	}
+
+	// Destructive changes, hidden until toggled
+	if len(m.destructive) > 0 {
+		items := make([]teamsElement, 0, l.destructive+1)
+		for _, d := range m.destructive[:l.destructive] {
+			items = append(items, teamsElement{Type: "TextBlock", Text: clip(d, teamsMaxText), FontType: "Monospace", Color: "Attention", Spacing: "None", Wrap: true})
+		}
+		if left := len(m.destructive) - l.destructive; left > 0 {
+			items = append(items, teamsElement{Type: "TextBlock", Text: fmt.Sprintf("…and %d more", left), IsSubtle: true, Wrap: true})
+		}
+		hidden := false
+		body = append(body,
+			teamsElement{Type: "ActionSet", Separator: true, Actions: []teamsAction{{
+				Type:           "Action.ToggleVisibility",
+				Title:          fmt.Sprintf("⚠️ Destructive Changes (%d)", len(m.destructive)),
+				TargetElements: []string{"destructive-changes"},
+			}}},
+			teamsElement{Type: "Container", ID: "destructive-changes", IsVisible: &hidden, Items: items})
+	}
+
+	body = append(body, teamsElement{Type: "TextBlock", Text: m.footer(), Size: "Small", IsSubtle: true, Wrap: true, Separator: true})
+
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    body,
+		MSTeams: map[string]string{"width": "Full"},
+	}
+	if f.ArtifactURL != "" {
+		card.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "Full report", URL: f.ArtifactURL}}
+	}
+
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	}
+}
+
+// teamsWarning renders a warning as a text block colored by level
+func teamsWarning(w analyzer.Warning) teamsElement {
+	text := fmt.Sprintf("%s **%s**", levelIcon(w.Level), clip(w.Message, teamsMaxText))
+	if w.Resource != "" {
+		text += " · " + clip(w.Resource, teamsMaxText)
+	}
+
+	color := "Default"
+	switch w.Level {
+	case analyzer.RiskCritical:
+		color = "Attention"
+	case analyzer.RiskHigh:
+		color = "Warning"
+	}
+	return teamsElement{Type: "TextBlock", Text: text, Color: color, Wrap: true}
+}
@@ -0,0 +1,408 @@
+package formatter
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// The markdown layout, rendered by MarkdownFormatter
+//
+//go:embed templates/markdown.tmpl
+var defaultTemplate string
+
+// TemplateData is the view model passed to report templates
+type TemplateData struct {
+	// Summary holds the counters and all parsed changes of the plan
+	Summary *parser.PlanSummary
+
+	// Changes are the changed resources by action, in plan order
+	Changes TemplateChanges
+
+	// Groups are the changes grouped by GroupBy, sorted by key; empty when
+	// grouping by action
+	GroupBy    GroupBy
+	GroupTitle string // e.g. "Module"
+	Groups     []TemplateGroup
+
+	// Reads are the data sources read during apply
+	Reads []TemplateChange
+
+	Score        *analyzer.PlanScore // nil when hidden
+	ModuleScores []ModuleScore       // highest first, only modules with risky changes
+	PlanWarnings []analyzer.Warning  // plan-wide anomalies such as mass deletions
+	Warnings     []analyzer.Warning  // resource warnings
+	Moves        []analyzer.MoveSuggestion
+
+	Options TemplateOptions
+	Meta    TemplateMeta
+}
+
+// TemplateChanges are the changes of a plan by action
+type TemplateChanges struct {
+	Create  []TemplateChange
+	Update  []TemplateChange
+	Replace []TemplateChange
+	Delete  []TemplateChange
+}
+
+// TemplateChange is a resource change with its attribute diff and warnings
+type TemplateChange struct {
+	parser.ResourceChange
+
+	Action string // create, update, replace, delete or read
+	Symbol string // +, ~, ⟳, - or <=
+
+	// Attributes are the changed attributes in display order
+	Attributes []TemplateAttribute
+
+	// Warnings are the resource warnings about this change
+	Warnings []analyzer.Warning
+}
+
+// TemplateAttribute is a changed attribute. Values are rendered for
+// display: sensitive values are masked and unknown ones read
+// "(known after apply)".
+type TemplateAttribute struct {
+	Key    string
+	Op     string // + added, - removed, ~ changed
+	Before string
+	After  string
+
+	// Diff is set for long, multiline, JSON and YAML strings, which are
+	// shown as a unified diff rather than Before and After
+	Diff *TemplateDiff
+}
+
+// String renders the attribute change, e.g. `~ size: 1 → 2`
+func (a TemplateAttribute) String() string {
+	switch a.Op {
+	case "+":
+		return "+ " + a.Key + ": " + a.After
+	case "-":
+		return "- " + a.Key + ": " + a.Before
+	}
+	return "~ " + a.Key + ": " + a.Before + " → " + a.After
+}
+
+// TemplateDiff is the unified diff of a string attribute
+type TemplateDiff struct {
+	Kind  string // JSON, YAML or text
+	Hunks []TemplateHunk
+}
+
+// TemplateHunk is a hunk of a unified diff. Lines start with +, - or a
+// space.
+type TemplateHunk struct {
+	Header string // e.g. "@@ -3,7 +3,8 @@"
+	Lines  []string
+}
+
+// TemplateGroup holds the changes sharing a module, provider or type
+type TemplateGroup struct {
+	Key     string // empty for the root module
+	Label   string // e.g. "(root)"
+	Changes []TemplateChange
+	Create  int
+	Update  int
+	Replace int
+	Delete  int
+}
+
+// ModuleScore is the risk score of a module
+type ModuleScore struct {
+	Module string // empty for the root module
+	Label  string // e.g. "(root)"
+	Score  float64
+}
+
+// TemplateOptions are the display options of the formatter
+type TemplateOptions struct {
+	ShowDetails   bool
+	Compact       bool
+	ShowUnchanged bool
+}
+
+// TemplateMeta describes the report
+type TemplateMeta struct {
+	TerraformVersion string
+	FormatVersion    string
+	Version          string    // InfraSync version
+	Generated        time.Time // when the report was rendered
+	Stack            string    // name of the plan in multi-plan reports
+}
+
+// templateFuncs are the helper functions available in templates. Text
+// helpers such as upper also accept values like risk levels.
+var templateFuncs = template.FuncMap{
+	// code wraps text in backticks
+	"code": func(v interface{}) string { return "`" + fmt.Sprint(v) + "`" },
+	// join joins strings: {{ $names | join ", " }}
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+	"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+	"lower": func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+	// title upper-cases the first letter
+	"title": func(v interface{}) string {
+		s := fmt.Sprint(v)
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+	"contains":  strings.Contains,
+	"replace":   func(old, repl, s string) string { return strings.ReplaceAll(s, old, repl) },
+	"trimSpace": strings.TrimSpace,
+	// plural appends an s unless n is 1: {{ plural 2 "resource" }}
+	"plural": func(n int, word string) string {
+		if n == 1 {
+			return word
+		}
+		return word + "s"
+	},
+	"add": func(a, b int) int { return a + b },
+	// percent renders a ratio, e.g. 0.9 as 90%
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	// truncate cuts s to n characters, ending with ...
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if len(r) <= n {
+			return s
+		}
+		return string(r[:max(n-3, 0)]) + "..."
+	},
+	// indent prefixes every line with n spaces
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	// json renders a value as indented JSON
+	"json": func(v interface{}) (string, error) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		return string(data), err
+	},
+	// level keeps the warnings of a risk level: {{ .Warnings | level "critical" }}
+	"level": func(l string, warnings []analyzer.Warning) []analyzer.Warning {
+		return filterWarnings(warnings, analyzer.RiskLevel(l))
+	},
+	"moduleLabel": moduleLabel,
+	// time formats a time with a Go layout: {{ .Meta.Generated | time "2006-01-02" }}
+	"time": func(layout string, t time.Time) string { return t.Format(layout) },
+}
+
+// DefaultTemplate returns the built-in markdown template. It defines the
+// sections "banner", "overview", "changes", "reads", "footer", "warnings"
+// and "moves", assembled by the "markdown" template, and by the
+// "prioritized" one for reports too long for MaxLength.
+func DefaultTemplate() *template.Template {
+	return template.Must(template.New("default").Funcs(templateFuncs).Parse(defaultTemplate))
+}
+
+// ParseTemplate parses a report template. The sections of the default
+// template are available to it, e.g. {{ template "warnings" . }}, and can
+// be redefined; {{ template "markdown" . }} renders the default layout
+// with the redefined sections.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	t, err := DefaultTemplate().New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// ParseTemplateFile reads and parses a report template file
+func ParseTemplateFile(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template: %w", err)
+	}
+	return ParseTemplate(filepath.Base(path), string(data))
+}
+
+// TemplateFormatter renders the report with a text/template, such as one
+// returned by ParseTemplateFile
+type TemplateFormatter struct {
+	Template      *template.Template // nil renders DefaultTemplate
+	ShowDetails   bool
+	CompactMode   bool
+	ShowUnchanged bool
+	Order         AttributeOrder
+	GroupBy       GroupBy
+	Meta          TemplateMeta // versions are taken from the plan when empty
+
+	// MaxLength limits the length of the output, as for MarkdownFormatter
+	MaxLength   int
+	Overflow    Overflow
+	ArtifactURL string
+
+	Analysis
+}
+
+// Format executes the template with the view model of the plan. Output
+// longer than MaxLength is split or truncated like MarkdownFormatter
+// output, without leaving out details.
+func (f *TemplateFormatter) Format(w io.Writer, summary *parser.PlanSummary) error {
+	doc, err := f.render(summary)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, strings.Join(f.fit(doc, "\n<details>\n"), ""))
+	return err
+}
+
+// FormatStacks executes the template for each stack, under a heading
+// with its name. The formatter's own analysis results are ignored.
+func (f *TemplateFormatter) FormatStacks(w io.Writer, stacks []Stack) error {
+	var sb strings.Builder
+	for i, s := range stacks {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("# 📦 Stack: %s\n\n", s.Name))
+
+		sf := *f
+		sf.Analysis = s.Analysis
+		sf.Meta.Stack = s.Name
+		doc, err := sf.render(s.Summary)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+		sb.WriteString(doc)
+	}
+	_, err := io.WriteString(w, strings.Join(f.fit(sb.String(), "\n# 📦 Stack: ", "\n<details>\n"), ""))
+	return err
+}
+
+func (f *TemplateFormatter) render(summary *parser.PlanSummary) (string, error) {
+	t := f.Template
+	if t == nil {
+		t = DefaultTemplate()
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, f.Data(summary)); err != nil {
+		return "", fmt.Errorf("error executing template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func (f *TemplateFormatter) fit(doc string, separators ...string) []string {
+	if f.MaxLength <= 0 {
+		return []string{doc}
+	}
+	md := &MarkdownFormatter{MaxLength: f.MaxLength, Overflow: f.Overflow, ArtifactURL: f.ArtifactURL}
+	return md.fit(doc, separators...)
+}
+
+// Data builds the view model of a plan
+func (f *TemplateFormatter) Data(summary *parser.PlanSummary) *TemplateData {
+	data := &TemplateData{
+		Summary:      summary,
+		GroupBy:      f.GroupBy,
+		Score:        f.Score,
+		PlanWarnings: f.PlanWarnings,
+		Warnings:     f.Warnings,
+		Moves:        f.Moves,
+		Options: TemplateOptions{
+			ShowDetails:   f.ShowDetails,
+			Compact:       f.CompactMode,
+			ShowUnchanged: f.ShowUnchanged,
+		},
+		Meta: f.Meta,
+	}
+	if data.GroupBy == "" {
+		data.GroupBy = GroupByAction
+	}
+	data.GroupTitle = data.GroupBy.title()
+	if data.Meta.TerraformVersion == "" {
+		data.Meta.TerraformVersion = summary.TerraformVersion
+	}
+	if data.Meta.FormatVersion == "" {
+		data.Meta.FormatVersion = summary.FormatVersion
+	}
+	if data.Meta.Generated.IsZero() {
+		data.Meta.Generated = time.Now()
+	}
+
+	for _, c := range summary.Changes {
+		tc := f.templateChange(c)
+		switch tc.Action {
+		case "create":
+			data.Changes.Create = append(data.Changes.Create, tc)
+		case "update":
+			data.Changes.Update = append(data.Changes.Update, tc)
+		case "replace":
+			data.Changes.Replace = append(data.Changes.Replace, tc)
+		case "delete":
+			data.Changes.Delete = append(data.Changes.Delete, tc)
+		}
+	}
+	for _, c := range summary.DataSources {
+		if c.IsRead {
+			data.Reads = append(data.Reads, f.templateChange(c))
+		}
+	}
+
+	if data.GroupBy != GroupByAction {
+		for _, g := range groupChanges(summary.Changes, data.GroupBy) {
+			tg := TemplateGroup{
+				Key:     g.key,
+				Label:   groupLabel(data.GroupBy, g.key),
+				Create:  g.counts.ToCreate,
+				Update:  g.counts.ToUpdate,
+				Replace: g.counts.ToReplace,
+				Delete:  g.counts.ToDelete,
+			}
+			for _, c := range g.changes {
+				tg.Changes = append(tg.Changes, f.templateChange(c))
+			}
+			data.Groups = append(data.Groups, tg)
+		}
+	}
+
+	if f.Score != nil {
+		for _, m := range sortedModuleScores(f.Score) {
+			data.ModuleScores = append(data.ModuleScores, ModuleScore{Module: m.Module, Label: moduleLabel(m.Module), Score: m.Score})
+		}
+	}
+
+	return data
+}
+
+func (f *TemplateFormatter) templateChange(c parser.ResourceChange) TemplateChange {
+	tc := TemplateChange{ResourceChange: c, Action: c.Action()}
+	tc.Symbol, _ = changeSymbol(c)
+	if c.IsRead {
+		tc.Symbol = "<="
+	}
+
+	for _, a := range f.Order.attributeChanges(c) {
+		ta := TemplateAttribute{Key: a.Key, Op: a.Op, Before: valueString(a.Before), After: valueString(a.After)}
+		if d, ok := diffString(a); ok {
+			ta.Diff = &TemplateDiff{Kind: d.Kind}
+			for _, h := range d.Hunks {
+				th := TemplateHunk{Header: h.Header()}
+				for _, l := range h.Lines {
+					th.Lines = append(th.Lines, l.op+l.text)
+				}
+				ta.Diff.Hunks = append(ta.Diff.Hunks, th)
+			}
+		}
+		tc.Attributes = append(tc.Attributes, ta)
+	}
+
+	for _, w := range f.Warnings {
+		if w.Resource == c.Address {
+			tc.Warnings = append(tc.Warnings, w)
+		}
+	}
+	return tc
+}
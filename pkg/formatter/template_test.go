@@ -0,0 +1,167 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTemplateFormatter_Golden(t *testing.T) {
+	tmpl, err := ParseTemplateFile(filepath.Join("testdata", "custom.tmpl"))
+	if err != nil {
+		t.Fatalf("ParseTemplateFile failed: %v", err)
+	}
+
+	f := &TemplateFormatter{
+		Template: tmpl,
+		Meta:     TemplateMeta{Version: "1.2.3", Generated: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)},
+		Analysis: testAnalysis(),
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, loadPlan(t, "mixed.json")); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	assertGolden(t, "template_custom", buf.Bytes())
+}
+
+func TestParseTemplate_RedefineSection(t *testing.T) {
+	tmpl, err := ParseTemplate("report", `{{ define "footer" }}
+_Custom footer_
+{{ end -}}
+{{ template "markdown" . }}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := (&TemplateFormatter{Template: tmpl}).Format(&buf, loadPlan(t, "mixed.json")); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "\n_Custom footer_\n") {
+		t.Errorf("footer not redefined:\n%s", got)
+	}
+	if strings.Contains(got, "Generated by [InfraSync]") {
+		t.Errorf("default footer still rendered:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "## 🔄 Terraform Plan Summary\n") {
+		t.Errorf("default layout not rendered:\n%s", got)
+	}
+
+	// The default template is left untouched
+	var def bytes.Buffer
+	if err := (&TemplateFormatter{}).Format(&def, loadPlan(t, "mixed.json")); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(def.String(), "Generated by [InfraSync]") {
+		t.Errorf("redefinition leaked into the default template")
+	}
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	if _, err := ParseTemplate("bad", "{{ if }}"); err == nil || !strings.Contains(err.Error(), "error parsing template bad") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	if _, err := ParseTemplateFile(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+
+	tmpl, err := ParseTemplate("exec", "{{ .Summary.Nope }}")
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+	err = (&TemplateFormatter{Template: tmpl}).Format(&bytes.Buffer{}, loadPlan(t, "empty.json"))
+	if err == nil || !strings.Contains(err.Error(), "error executing template") {
+		t.Errorf("expected an execution error, got %v", err)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{ code "a.b" }}`, "`a.b`"},
+		{`{{ list "a" "b" | join ", " }}`, "a, b"},
+		{`{{ upper "ab" }} {{ lower "AB" }} {{ title "ab" }}`, "AB ab Ab"},
+		{`{{ contains "abc" "b" }} {{ replace "." "_" "a.b.c" }} {{ trimSpace " a " }}`, "true a_b_c a"},
+		{`{{ plural 1 "change" }} {{ plural 2 "change" }} {{ add 1 2 }} {{ percent 0.914 }}`, "change changes 3 91%"},
+		{`{{ truncate 5 "abcdefgh" }} {{ truncate 5 "abc" }}`, "ab... abc"},
+		{`{{ indent 2 "a\nb" }}`, "  a\n  b"},
+		{`{{ json (list "a") }}`, "[\n  \"a\"\n]"},
+		{`{{ moduleLabel "" }} {{ moduleLabel "module.vpc" }}`, "(root) module.vpc"},
+		{`{{ .Meta.Generated | time "2006-01-02" }}`, "2026-10-18"},
+		{`{{ range .Warnings | level "critical" }}{{ .Resource }}{{ end }}`, "aws_db_instance.prod"},
+	}
+
+	data := &TemplateData{
+		Warnings: testAnalysis().Warnings,
+		Meta:     TemplateMeta{Generated: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tmpl, err := template.New("t").Funcs(templateFuncs).Funcs(template.FuncMap{
+				"list": func(s ...string) []string { return s },
+			}).Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFormatter_Stacks(t *testing.T) {
+	tmpl, err := ParseTemplate("stack", "{{ .Meta.Stack }}: {{ .Summary.Counts.Changed }} changes\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+
+	stacks := []Stack{
+		{Name: "network", Summary: loadPlan(t, "modules.json")},
+		{Name: "app", Summary: loadPlan(t, "mixed.json")},
+	}
+	var buf bytes.Buffer
+	if err := (&TemplateFormatter{Template: tmpl}).FormatStacks(&buf, stacks); err != nil {
+		t.Fatalf("FormatStacks failed: %v", err)
+	}
+	want := fmt.Sprintf("# 📦 Stack: network\n\nnetwork: %d changes\n\n# 📦 Stack: app\n\napp: %d changes\n",
+		stacks[0].Summary.Counts().Changed(), stacks[1].Summary.Counts().Changed())
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTemplateFormatter_MaxLength(t *testing.T) {
+	summary := largePlan(200)
+	f := &TemplateFormatter{ShowDetails: true, MaxLength: 4000, Analysis: testAnalysis()}
+
+	var sb strings.Builder
+	if err := f.Format(&sb, summary); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	parts := strings.Split(sb.String(), "<!-- infrasync:part ")[1:]
+	if len(parts) < 3 {
+		t.Fatalf("Format() wrote %d parts, want several", len(parts))
+	}
+	for i, p := range parts {
+		if len("<!-- infrasync:part ")+len(p) > f.MaxLength {
+			t.Errorf("part %d is too long", i+1)
+		}
+		if strings.Count(p, "```")%2 != 0 {
+			t.Errorf("part %d has unbalanced code blocks", i+1)
+		}
+	}
+}
@@ -0,0 +1,745 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// TerraformFormatter renders the plan in the block syntax of `terraform
+// plan`, with the warnings of each resource right above its block
+type TerraformFormatter struct {
+	Color ColorMode
+	Order AttributeOrder
+	Analysis
+}
+
+// Attributes shown even when unchanged, so that updated resources can be
+// recognized, as Terraform does
+var identifyingAttributes = map[string]bool{"id": true, "name": true}
+
+// Format writes the plan summary and analysis results to w, colored when
+// the color mode allows it for w
+func (f *TerraformFormatter) Format(w io.Writer, summary *parser.PlanSummary) error {
+	t := &terminal{w: w, color: f.Color.Enabled(w)}
+	cli := &CLIFormatter{Analysis: f.Analysis}
+
+	cli.printPlanBanner(t)
+	f.printPlan(t, summary)
+	f.printOtherWarnings(t, summary)
+	if len(f.Moves) > 0 {
+		t.printf(plain, "\n")
+	}
+	cli.printMovedBlocks(t)
+
+	return t.err
+}
+
+func (f *TerraformFormatter) printPlan(t *terminal, summary *parser.PlanSummary) {
+	changes := make([]parser.ResourceChange, 0, len(summary.Changes)+len(summary.DataSources))
+	for _, c := range summary.Changes {
+		if !c.IsNoOp {
+			changes = append(changes, c)
+		}
+	}
+	for _, c := range summary.DataSources {
+		if c.IsRead {
+			changes = append(changes, c)
+		}
+	}
+
+	t.printf(plain, "\n")
+	switch {
+	case len(changes) == 0 && summary.Total != nil:
+		t.println(green, "No changes match the filter.")
+		return
+	case len(changes) == 0:
+		t.println(green, "No changes. Your infrastructure matches the configuration.")
+		return
+	}
+
+	f.printLegend(t, changes)
+	t.println(plain, "Terraform will perform the following actions:")
+	for _, c := range changes {
+		t.printf(plain, "\n")
+		f.printResource(t, c)
+	}
+
+	t.printf(plain, "\n")
+	t.println(plain, "Plan: %d to add, %d to change, %d to destroy.",
+		summary.ToCreate+summary.ToReplace, summary.ToUpdate, summary.ToDelete+summary.ToReplace)
+	if summary.Total != nil {
+		t.println(hiBlack, "(filtered: %d of %d changed resources)", summary.Counts().Changed(), summary.Total.Changed())
+	}
+	if f.Score != nil {
+		t.println(hiBlack, "InfraSync risk score: %.0f (%s)", f.Score.Total, f.Score.Level)
+	}
+}
+
+// printLegend explains the markers used by the plan
+func (f *TerraformFormatter) printLegend(t *terminal, changes []parser.ResourceChange) {
+	used := make(map[string]bool)
+	for _, c := range changes {
+		marker, _ := resourceMarker(c)
+		used[marker] = true
+	}
+
+	t.println(plain, "Resource actions are indicated with the following symbols:")
+	legend := []struct {
+		marker, text string
+	}{
+		{"+", "create"},
+		{"~", "update in-place"},
+		{"-", "destroy"},
+		{"-/+", "destroy and then create replacement"},
+		{"+/-", "create replacement and then destroy"},
+		{"<=", "read (data resources)"},
+	}
+	for _, l := range legend {
+		if used[l.marker] {
+			s := markerStyle(l.marker)
+			t.printf(s, "%3s", l.marker)
+			t.println(plain, " %s", l.text)
+		}
+	}
+	t.printf(plain, "\n")
+}
+
+func (f *TerraformFormatter) printResource(t *terminal, c parser.ResourceChange) {
+	marker, action := resourceMarker(c)
+	s := markerStyle(marker)
+
+	t.println(bold, "  # %s %s", c.Address, action)
+	for _, w := range f.Warnings {
+		if w.Resource != c.Address {
+			continue
+		}
+		ws := warningStyle(w.Level)
+		t.println(ws, "  # ⚠ %s %s: %s", strings.ToUpper(string(w.Level)), w.Rule, w.Message)
+		if w.Explanation != "" {
+			t.println(hiBlack, "  #   %s", w.Explanation)
+		}
+	}
+
+	keyword := "resource"
+	if c.IsDataSource() {
+		keyword = "data"
+	}
+
+	r := &planRenderer{t: t, replace: replacePaths(c.ReplacePaths)}
+	root := diffNode{
+		before:     c.Before,
+		after:      c.After,
+		hasBefore:  c.Before != nil,
+		hasAfter:   c.After != nil,
+		unknown:    c.AfterUnknown,
+		beforeSens: c.BeforeSensitive,
+		afterSens:  c.AfterSensitive,
+	}
+
+	r.line("", marker, s, fmt.Sprintf("%s %q %q {", keyword, c.Type, resourceName(c)), false)
+	r.writeBody("", root, f.Order.keys(c.Type, c.Before, c.After, unknownKeys(c)), true)
+	r.line("", "", plain, "}", false)
+}
+
+// printOtherWarnings lists the warnings about resources that aren't shown
+// in the plan, e.g. because they are unchanged
+func (f *TerraformFormatter) printOtherWarnings(t *terminal, summary *parser.PlanSummary) {
+	shown := make(map[string]bool)
+	for _, c := range summary.Changes {
+		shown[c.Address] = !c.IsNoOp
+	}
+	for _, c := range summary.DataSources {
+		shown[c.Address] = c.IsRead
+	}
+
+	others := make([]analyzer.Warning, 0)
+	for _, w := range f.Warnings {
+		if !shown[w.Resource] {
+			others = append(others, w)
+		}
+	}
+	if len(others) == 0 {
+		return
+	}
+
+	t.printf(plain, "\n")
+	t.println(plain, "InfraSync warnings about other resources:")
+	for _, w := range others {
+		t.println(warningStyle(w.Level), "  ⚠ %s %s: %s", strings.ToUpper(string(w.Level)), w.Rule, w.Message)
+		if w.Resource != "" {
+			t.println(hiBlack, "    Resource: %s", w.Resource)
+		}
+	}
+}
+
+// resourceMarker returns the marker of a change and the action shown in
+// its heading
+func resourceMarker(c parser.ResourceChange) (string, string) {
+	switch {
+	case c.IsDataSource():
+		return "<=", "will be read during apply"
+	case c.IsCreate:
+		return "+", "will be created"
+	case c.IsUpdate:
+		return "~", "will be updated in-place"
+	case c.IsReplace:
+		if len(c.Actions) > 0 && c.Actions[0] == "create" {
+			return "+/-", "must be replaced"
+		}
+		return "-/+", "must be replaced"
+	case c.IsDelete:
+		return "-", "will be destroyed"
+	}
+	return " ", "has no changes"
+}
+
+// markerStyle returns the style of a marker
+func markerStyle(op string) style {
+	switch op {
+	case "+":
+		return green
+	case "-":
+		return red
+	case "~":
+		return yellow
+	case "-/+", "+/-":
+		return magenta
+	case "<=":
+		return cyan
+	}
+	return plain
+}
+
+func warningStyle(level analyzer.RiskLevel) style {
+	switch level {
+	case analyzer.RiskCritical:
+		return red
+	case analyzer.RiskHigh:
+		return yellow
+	case analyzer.RiskMedium:
+		return cyan
+	}
+	return hiBlack
+}
+
+// resourceName returns the name of a resource, taken from its address for
+// changes parsed without one
+func resourceName(c parser.ResourceChange) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	name := c.Address
+	if i := strings.LastIndex(name, c.Type+"."); i >= 0 {
+		name = name[i+len(c.Type)+1:]
+	}
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// replacePaths indexes the paths that force a replacement by pathKey
+func replacePaths(paths []interface{}) map[string]bool {
+	result := make(map[string]bool)
+	for _, p := range paths {
+		steps, ok := p.([]interface{})
+		if !ok {
+			continue
+		}
+		key := ""
+		for _, s := range steps {
+			key = pathKey(key, s)
+		}
+		result[key] = true
+	}
+	return result
+}
+
+// pathKey appends an attribute name or list index to a path
+func pathKey(path string, step interface{}) string {
+	if f, ok := step.(float64); ok {
+		step = int(f)
+	}
+	return path + "/" + fmt.Sprint(step)
+}
+
+// diffNode is a value on both sides of a change, with the unknown and
+// sensitive marks that apply to it
+type diffNode struct {
+	before, after         interface{}
+	hasBefore, hasAfter   bool
+	unknown               interface{}
+	beforeSens, afterSens interface{}
+	path                  string
+}
+
+// child returns the attribute or element of the node at key, a string for
+// objects and maps or an int for lists
+func (n diffNode) child(key interface{}) diffNode {
+	c := diffNode{
+		unknown:    markAt(n.unknown, key),
+		beforeSens: markAt(n.beforeSens, key),
+		afterSens:  markAt(n.afterSens, key),
+		path:       pathKey(n.path, key),
+	}
+	c.before, c.hasBefore = valueAt(n.before, key)
+	c.after, c.hasAfter = valueAt(n.after, key)
+	return c
+}
+
+func valueAt(v interface{}, key interface{}) (interface{}, bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			c, exists := val[k]
+			return c, exists
+		}
+	case []interface{}:
+		if i, ok := key.(int); ok && i < len(val) {
+			return val[i], true
+		}
+	}
+	return nil, false
+}
+
+// markAt returns the marks of a child value. A mark of true covers the
+// whole value.
+func markAt(marks interface{}, key interface{}) interface{} {
+	if b, ok := marks.(bool); ok {
+		return b
+	}
+	v, _ := valueAt(marks, key)
+	return v
+}
+
+// hasMark reports whether any part of a value is marked
+func hasMark(marks interface{}) bool {
+	switch m := marks.(type) {
+	case bool:
+		return m
+	case map[string]interface{}:
+		for _, v := range m {
+			if hasMark(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range m {
+			if hasMark(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n diffNode) isUnknown() bool {
+	b, _ := n.unknown.(bool)
+	return b
+}
+
+func (n diffNode) isSensitive() bool {
+	before, _ := n.beforeSens.(bool)
+	after, _ := n.afterSens.(bool)
+	return before || after
+}
+
+// op returns + when the value is added, - when removed, ~ when changed, a
+// space when unchanged and "" when null on both sides
+func (n diffNode) op() string {
+	beforeNull := !n.hasBefore || n.before == nil
+	afterNull := !n.isUnknown() && (!n.hasAfter || n.after == nil)
+	switch {
+	case beforeNull && afterNull:
+		return ""
+	case beforeNull:
+		return "+"
+	case afterNull:
+		return "-"
+	case !hasMark(n.unknown) && reflect.DeepEqual(n.before, n.after):
+		return " "
+	}
+	return "~"
+}
+
+// isBlocks reports whether the node holds nested blocks, i.e. lists of
+// objects
+func (n diffNode) isBlocks() bool {
+	if n.isUnknown() {
+		return false
+	}
+	before, beforeOK := objectList(n.before)
+	after, afterOK := objectList(n.after)
+	return beforeOK && afterOK && before+after > 0
+}
+
+// objectList returns the length of a list of objects. Null counts as an
+// empty list.
+func objectList(v interface{}) (int, bool) {
+	if v == nil {
+		return 0, true
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return 0, false
+	}
+	for _, e := range list {
+		if _, ok := e.(map[string]interface{}); !ok {
+			return 0, false
+		}
+	}
+	return len(list), true
+}
+
+// isMap reports whether both sides are maps or null
+func (n diffNode) isMap() bool {
+	_, before := n.before.(map[string]interface{})
+	_, after := n.after.(map[string]interface{})
+	return (before || n.before == nil) && (after || n.after == nil) && (before || after)
+}
+
+// isList reports whether both sides are lists or null
+func (n diffNode) isList() bool {
+	_, before := n.before.([]interface{})
+	_, after := n.after.([]interface{})
+	return (before || n.before == nil) && (after || n.after == nil) && (before || after)
+}
+
+// isEmpty reports whether the non-null sides are empty maps or lists
+func (n diffNode) isEmpty() bool {
+	return length(n.before)+length(n.after) == 0
+}
+
+func length(v interface{}) int {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return len(val)
+	case []interface{}:
+		return len(val)
+	}
+	return 0
+}
+
+func (n diffNode) keys() []string {
+	keys := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range []interface{}{n.before, n.after, n.unknown} {
+		if m, ok := m.(map[string]interface{}); ok {
+			for k := range m {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// planRenderer writes the blocks of a resource
+type planRenderer struct {
+	t       *terminal
+	replace map[string]bool
+}
+
+// line writes a line of the plan: the marker right-aligned in three
+// columns after the padding, then the content
+func (r *planRenderer) line(pad, marker string, s style, content string, forces bool) {
+	r.t.printf(s, "%s%3s %s", pad, marker, content)
+	if forces {
+		r.t.printf(red, " # forces replacement")
+	}
+	r.t.printf(plain, "\n")
+}
+
+// comment writes a comment aligned with the content of the lines at pad
+func (r *planRenderer) comment(pad, format string, n int, noun string) {
+	if n == 0 {
+		return
+	}
+	if n != 1 {
+		noun += "s"
+	}
+	r.line(pad, "", hiBlack, fmt.Sprintf(format, n, noun), false)
+}
+
+// writeBody writes the attributes of an object followed by its nested
+// blocks. Unchanged ones are counted rather than shown, except identifying
+// attributes of the resource itself.
+func (r *planRenderer) writeBody(pad string, n diffNode, keys []string, resource bool) {
+	pad += "    "
+
+	type entry struct {
+		name string
+		node diffNode
+		op   string
+	}
+	attrs, blocks := make([]entry, 0), make([]entry, 0)
+	hidden, width := 0, 0
+	for _, key := range keys {
+		c := n.child(key)
+		op := c.op()
+		switch {
+		case op == "":
+			continue
+		case c.isBlocks():
+			blocks = append(blocks, entry{key, c, op})
+			continue
+		case op == " " && !(resource && identifyingAttributes[key]):
+			hidden++
+			continue
+		}
+		attrs = append(attrs, entry{key, c, op})
+		width = max(width, len(key))
+	}
+
+	for _, a := range attrs {
+		r.writeAttribute(pad, a.name+strings.Repeat(" ", width-len(a.name)), a.node, a.op)
+	}
+	r.comment(pad, "# (%d unchanged %s hidden)", hidden, "attribute")
+
+	hidden = 0
+	for _, b := range blocks {
+		hidden += r.writeBlocks(pad, b.name, b.node)
+	}
+	r.comment(pad, "# (%d unchanged %s hidden)", hidden, "block")
+}
+
+// writeMap writes the elements of a map with quoted keys
+func (r *planRenderer) writeMap(pad string, n diffNode) {
+	pad += "    "
+
+	type entry struct {
+		name string
+		node diffNode
+		op   string
+	}
+	entries := make([]entry, 0)
+	hidden, width := 0, 0
+	for _, key := range n.keys() {
+		c := n.child(key)
+		switch op := c.op(); op {
+		case "":
+		case " ":
+			hidden++
+		default:
+			name := strconv.Quote(key)
+			entries = append(entries, entry{name, c, op})
+			width = max(width, len(name))
+		}
+	}
+
+	for _, e := range entries {
+		r.writeAttribute(pad, e.name+strings.Repeat(" ", width-len(e.name)), e.node, e.op)
+	}
+	r.comment(pad, "# (%d unchanged %s hidden)", hidden, "element")
+}
+
+// writeBlocks writes nested blocks paired by position and returns the
+// number of unchanged ones left out
+func (r *planRenderer) writeBlocks(pad, name string, n diffNode) int {
+	before, _ := objectList(n.before)
+	after, _ := objectList(n.after)
+
+	hidden := 0
+	for i := 0; i < max(before, after); i++ {
+		c := n.child(i)
+		op := c.op()
+		if op == " " {
+			hidden++
+			continue
+		}
+		s := markerStyle(op)
+		r.line(pad, op, s, name+" {", r.forces(c))
+		r.writeBody(pad, c, c.keys(), false)
+		r.line(pad, "", plain, "}", false)
+	}
+	return hidden
+}
+
+func (r *planRenderer) forces(n diffNode) bool {
+	return r.replace[n.path]
+}
+
+// writeAttribute writes `name = value`, spreading maps, lists and
+// multiline strings over several lines
+func (r *planRenderer) writeAttribute(pad, name string, n diffNode, op string) {
+	s := markerStyle(op)
+	forces := r.forces(n)
+	prefix := name + " = "
+
+	switch {
+	case n.isSensitive():
+		r.line(pad, op, s, prefix+"(sensitive value)"+nullSuffix(op), forces)
+	case n.isUnknown():
+		value := "(known after apply)"
+		if op == "~" && isScalar(n.before) {
+			value = hclValue(n.before) + " -> " + value
+		}
+		r.line(pad, op, s, prefix+value, forces)
+	case (n.isMap() || n.isList()) && n.isEmpty():
+		r.writeScalar(pad, prefix, n, op, forces)
+	case n.isMap():
+		r.line(pad, op, s, prefix+"{", forces)
+		r.writeMap(pad, n)
+		r.line(pad, "", plain, "}"+nullSuffix(op), false)
+	case n.isList():
+		r.line(pad, op, s, prefix+"[", forces)
+		r.writeList(pad, n)
+		r.line(pad, "", plain, "]"+nullSuffix(op), false)
+	case isMultiline(n.before) || isMultiline(n.after):
+		r.writeHeredoc(pad, prefix, n, op, forces)
+	default:
+		r.writeScalar(pad, prefix, n, op, forces)
+	}
+}
+
+func (r *planRenderer) writeScalar(pad, prefix string, n diffNode, op string, forces bool) {
+	s := markerStyle(op)
+	switch op {
+	case "+", " ":
+		r.line(pad, op, s, prefix+hclValue(n.after), forces)
+	case "-":
+		r.line(pad, op, s, prefix+hclValue(n.before)+" -> null", forces)
+	default:
+		r.line(pad, op, s, prefix+hclValue(n.before)+" -> "+hclValue(n.after), forces)
+	}
+}
+
+// writeList writes the elements of a list, with the elements that were
+// added or removed marked
+func (r *planRenderer) writeList(pad string, n diffNode) {
+	pad += "    "
+	before := listElements(n, false)
+	after := listElements(n, true)
+	for _, d := range diffLines(before, after) {
+		s := markerStyle(d.op)
+		r.line(pad, d.op, s, d.text+",", false)
+	}
+}
+
+// listElements renders the elements of one side of a list
+func listElements(n diffNode, after bool) []string {
+	list, _ := n.before.([]interface{})
+	if after {
+		list, _ = n.after.([]interface{})
+	}
+	elements := make([]string, 0, len(list))
+	for i, e := range list {
+		c := n.child(i)
+		sensitive, unknown := c.beforeSens, interface{}(nil)
+		if after {
+			sensitive, unknown = c.afterSens, c.unknown
+		}
+		switch {
+		case unknown == true:
+			elements = append(elements, "(known after apply)")
+		case sensitive == true:
+			elements = append(elements, "(sensitive value)")
+		default:
+			elements = append(elements, hclValue(e))
+		}
+	}
+	return elements
+}
+
+// writeHeredoc writes a multiline string as a heredoc, with changed lines
+// marked when both sides are strings
+func (r *planRenderer) writeHeredoc(pad, prefix string, n diffNode, op string, forces bool) {
+	s := markerStyle(op)
+	before, beforeOK := n.before.(string)
+	after, afterOK := n.after.(string)
+	if op == "~" && !(beforeOK && afterOK) {
+		r.writeScalar(pad, prefix, n, op, forces)
+		return
+	}
+
+	r.line(pad, op, s, prefix+"<<-EOT", forces)
+	var lines []diffLine
+	switch op {
+	case "+", " ":
+		lines = diffLines(nil, textLines(after))
+	case "-":
+		lines = diffLines(textLines(before), nil)
+	default:
+		lines = diffLines(textLines(before), textLines(after))
+	}
+	for _, l := range lines {
+		marker := l.op
+		if op != "~" {
+			marker = " "
+		}
+		ls := markerStyle(marker)
+		r.line(pad+"    ", marker, ls, l.text, false)
+	}
+	r.line(pad, "", plain, "EOT"+nullSuffix(op), false)
+}
+
+func nullSuffix(op string) string {
+	if op == "-" {
+		return " -> null"
+	}
+	return ""
+}
+
+func isMultiline(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// hclValue renders a value as an HCL literal. Nested maps and lists are
+// written on one line.
+func hclValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, e := range val {
+			parts = append(parts, hclValue(e))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(val))
+		for _, k := range keys {
+			parts = append(parts, strconv.Quote(k)+" = "+hclValue(val[k]))
+		}
+		if len(parts) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
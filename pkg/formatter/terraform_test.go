@@ -0,0 +1,47 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestTerraformFormatter_Golden(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      string
+		formatter *TerraformFormatter
+	}{
+		{"terraform_empty", "empty.json", &TerraformFormatter{}},
+		{"terraform_plan", "terraform.json", &TerraformFormatter{Analysis: testAnalysis()}},
+		{"terraform_mixed", "mixed.json", &TerraformFormatter{}},
+		{"terraform_values", "values.json", &TerraformFormatter{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.formatter.Format(&buf, loadPlan(t, tt.plan)); err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	tests := []struct {
+		address, typ, want string
+	}{
+		{"aws_instance.web", "aws_instance", "web"},
+		{"module.app.aws_instance.web[0]", "aws_instance", "web"},
+		{`aws_instance.web["a.b"]`, "aws_instance", "web"},
+	}
+	for _, tt := range tests {
+		c := parser.ResourceChange{Address: tt.address, Type: tt.typ}
+		if got := resourceName(c); got != tt.want {
+			t.Errorf("resourceName(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package formatter
+
+import (
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// attributeChange is a changed top-level attribute of a resource
+type attributeChange struct {
+	Key    string
+	Op     string // + added, - removed, ~ changed
+	Before parser.Value
+	After  parser.Value
+}
+
+// attributeChanges compares the attributes of a change in display order.
+// Attributes only known after apply count as changed, even when they are
+// left out of the planned values.
+func (o AttributeOrder) attributeChanges(change parser.ResourceChange) []attributeChange {
+	changes := make([]attributeChange, 0)
+	for _, key := range o.keys(change.Type, change.Before, change.After, unknownKeys(change)) {
+		before, after := change.BeforeValue(key), change.AfterValue(key)
+
+		var op string
+		switch {
+		case before.State == parser.ValueAbsent && after.State == parser.ValueAbsent:
+			continue
+		case before.State == parser.ValueAbsent:
+			op = "+"
+		case after.State == parser.ValueAbsent:
+			op = "-"
+		case before.Equal(after):
+			continue
+		default:
+			op = "~"
+		}
+		changes = append(changes, attributeChange{Key: key, Op: op, Before: before, After: after})
+	}
+	return changes
+}
+
+// attributeValue is a top-level attribute on one side of a change
+type attributeValue struct {
+	Key   string
+	Value parser.Value
+}
+
+// attributeValues returns the attributes of one side of a change in
+// display order, including the ones only known after apply
+func (o AttributeOrder) attributeValues(change parser.ResourceChange, after bool) []attributeValue {
+	values, value := change.Before, change.BeforeValue
+	var unknown map[string]interface{}
+	if after {
+		values, value, unknown = change.After, change.AfterValue, unknownKeys(change)
+	}
+
+	result := make([]attributeValue, 0, len(values))
+	for _, key := range o.keys(change.Type, values, unknown) {
+		if v := value(key); v.State != parser.ValueAbsent {
+			result = append(result, attributeValue{Key: key, Value: v})
+		}
+	}
+	return result
+}
+
+// String renders the change, e.g. `~ size: 1 → 2`
+func (c attributeChange) String() string {
+	switch c.Op {
+	case "+":
+		return "+ " + c.Key + ": " + valueString(c.After)
+	case "-":
+		return "- " + c.Key + ": " + valueString(c.Before)
+	}
+	return "~ " + c.Key + ": " + valueString(c.Before) + " → " + valueString(c.After)
+}
+
+// valueString renders a value, never revealing sensitive values
+func valueString(v parser.Value) string {
+	switch {
+	case v.Sensitive:
+		return "(sensitive)"
+	case v.State == parser.ValueUnknown:
+		return "(known after apply)"
+	case v.State == parser.ValueNull, v.State == parser.ValueAbsent:
+		return "null"
+	}
+	return formatValue(v.Raw)
+}
+
+func unknownKeys(change parser.ResourceChange) map[string]interface{} {
+	keys := make(map[string]interface{})
+	for _, k := range change.UnknownAttributes() {
+		keys[k] = true
+	}
+	return keys
+}
@@ -0,0 +1,98 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+)
+
+func filterWarnings(warnings []analyzer.Warning, level analyzer.RiskLevel) []analyzer.Warning {
+	result := make([]analyzer.Warning, 0)
+	for _, w := range warnings {
+		if w.Level == level {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+func (f *CLIFormatter) printPlanBanner(t *terminal) {
+	if len(f.PlanWarnings) == 0 {
+		return
+	}
+
+	t.printf(plain, "\n")
+	t.printf(redBanner, " 🚨 MASS CHANGE DETECTED ")
+	t.printf(plain, "\n\n")
+
+	for _, w := range f.PlanWarnings {
+		t.println(red, "  • %s", w.Message)
+		t.println(hiBlack, "    %s", w.Explanation)
+	}
+
+	t.println(yellow, "\n  Check the workspace, var files and provider versions before applying.")
+}
+
+func (f *CLIFormatter) printWarnings(t *terminal) {
+	if len(f.Warnings) == 0 {
+		return
+	}
+
+	t.printf(plain, "\n")
+	t.printf(yellowBanner, " 🔍 SECURITY & RISK ANALYSIS ")
+	t.printf(plain, "\n\n")
+
+	criticals := filterWarnings(f.Warnings, analyzer.RiskCritical)
+	highs := filterWarnings(f.Warnings, analyzer.RiskHigh)
+	mediums := filterWarnings(f.Warnings, analyzer.RiskMedium)
+
+	if len(criticals) > 0 {
+		t.println(red, "🚨 CRITICAL WARNINGS (%d):", len(criticals))
+		t.println(red, "─────────────────────────")
+		for _, w := range criticals {
+			t.println(red, "  • %s", w.Message)
+			t.println(hiBlack, "    Resource: %s", w.Resource)
+			t.println(hiBlack, "    %s", w.Explanation)
+		}
+		t.printf(plain, "\n")
+	}
+
+	if len(highs) > 0 {
+		t.println(yellow, "⚠️  HIGH RISK WARNINGS (%d):", len(highs))
+		t.println(yellow, "──────────────────────────")
+		for _, w := range highs {
+			t.println(yellow, "  • %s", w.Message)
+			t.println(hiBlack, "    Resource: %s", w.Resource)
+			t.println(hiBlack, "    %s", w.Explanation)
+		}
+		t.printf(plain, "\n")
+	}
+
+	if len(mediums) > 0 {
+		t.println(cyan, "ℹ️  MEDIUM RISK WARNINGS (%d):", len(mediums))
+		t.println(cyan, "────────────────────────────")
+		for _, w := range mediums {
+			t.println(cyan, "  • %s", w.Message)
+			t.println(hiBlack, "    Resource: %s", w.Resource)
+		}
+		t.printf(plain, "\n")
+	}
+}
+
+func (f *CLIFormatter) printMovedBlocks(t *terminal) {
+	if len(f.Moves) == 0 {
+		return
+	}
+
+	t.println(cyan, "📦 SUGGESTED MOVED BLOCKS (%d):", len(f.Moves))
+	t.println(cyan, "─────────────────────────────")
+	t.println(hiBlack, "  These resources look renamed. Add the blocks below to keep them instead of recreating them.")
+	t.printf(plain, "\n")
+	for _, m := range f.Moves {
+		for _, line := range strings.Split(strings.TrimSuffix(m.Block(), "\n"), "\n") {
+			t.printf(plain, "  %s\n", line)
+		}
+		t.println(hiBlack, "  # %s, %.0f%% of attributes match", m.Type, m.Similarity*100)
+		t.printf(plain, "\n")
+	}
+}
@@ -0,0 +1,30 @@
+// Package infrasync is the library entry point of InfraSync. It parses a
+// Terraform plan, runs every analyzer the command line tool runs and
+// renders the result in any of the supported output formats:
+//
+//	report, err := infrasync.Analyze(ctx, planJSON, infrasync.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	if err := report.Format(w, "markdown", infrasync.FormatOptions{}); err != nil {
+//		return err
+//	}
+//	os.Exit(report.ExitCode())
+//
+// Programs analyzing many plans with the same configuration should create
+// an Analyzer once with New, so that rules, policies and plugins are only
+// loaded once.
+//
+// # Compatibility
+//
+// This package follows semantic versioning from release 0.3.0 on: exported
+// identifiers are not removed or changed incompatibly within a major
+// version. New fields may be added to Options, FormatOptions and Report,
+// and new formats and exit codes may be added; the zero value of new
+// option fields keeps the previous behavior. The rendered text of the
+// human readable formats is not covered and may change in any release;
+// use the json format or the Report fields for machine processing.
+//
+// Types from the parser, analyzer and config packages are part of this API
+// through Report and Options and follow the same guarantees.
+package infrasync
@@ -0,0 +1,77 @@
+package infrasync_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/config"
+	"github.com/kvizadsaderah/infrasync/pkg/infrasync"
+)
+
+func ExampleAnalyze() {
+	plan, err := os.Open("testdata/plan.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer plan.Close()
+
+	report, err := infrasync.Analyze(context.Background(), plan, infrasync.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, w := range report.AllWarnings() {
+		fmt.Printf("%-8s %-20s %s\n", w.Level, w.Rule, w.Resource)
+	}
+	fmt.Println("exit code:", report.ExitCode())
+	// Output:
+	// critical mass-deletion        plan
+	// critical production-deletion  aws_db_instance.prod
+	// critical database-deletion    aws_db_instance.prod
+	// high     storage-deletion     aws_s3_bucket.a
+	// high     missing-moved-block  aws_s3_bucket.a
+	// exit code: 2
+}
+
+func ExampleReport_Format() {
+	plan, err := os.Open("testdata/plan.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer plan.Close()
+
+	report, err := infrasync.Analyze(context.Background(), plan, infrasync.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := report.Format(&sb, "markdown", infrasync.FormatOptions{Compact: true}); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(strings.SplitN(sb.String(), "\n", 2)[0])
+	// Output:
+	// ## 🚨 Mass Change Detected
+}
+
+func ExampleNew() {
+	cfg := config.Default()
+	cfg.MassChange.Plan.DeleteRatio = 0 // tolerate large deletions
+
+	a, err := infrasync.New(context.Background(), infrasync.Options{Config: cfg})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The analyzer can be reused for any number of plans
+	report, err := a.AnalyzeFile(context.Background(), "testdata/plan.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(len(report.PlanWarnings), "plan-wide warnings")
+	// Output:
+	// 0 plan-wide warnings
+}
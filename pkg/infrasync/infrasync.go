@@ -0,0 +1,211 @@
+package infrasync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/config"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+	"github.com/kvizadsaderah/infrasync/pkg/plugin"
+	"github.com/kvizadsaderah/infrasync/pkg/policy"
+)
+
+// Options configures an analysis. The zero value runs every analyzer with
+// the default configuration.
+type Options struct {
+	// Config holds thresholds, score weights, custom rules, Rego policies
+	// and plugins. Nil means config.Default().
+	Config *config.Config
+
+	// ProviderVersions are the provider versions selected by the lock
+	// file, by source address (see parser.ParseLockFile)
+	ProviderVersions map[string]string
+
+	// Previous is the plan to compare Terraform, provider and schema
+	// versions against. Nil disables the comparison.
+	Previous *parser.PlanSummary
+
+	// SkipWarnings only parses and scores the plan, without running rules,
+	// policies and plugins
+	SkipWarnings bool
+
+	// Version is reported to plugins as the InfraSync version
+	Version string
+
+	// Filter narrows the plan down before analysis, so warnings, score and
+	// output only cover the kept resources. The zero value keeps all.
+	Filter parser.Filter
+
+	// Terraform is the executable rendering binary plan files as JSON with
+	// `show -json`. Empty means terraform from the PATH.
+	Terraform string
+}
+
+// Analyzer runs the analysis with rules, policies and plugins loaded once.
+// It is safe for concurrent use.
+type Analyzer struct {
+	opts    Options
+	cfg     *config.Config
+	rules   []analyzer.Rule
+	rego    *policy.RegoPolicy
+	plugins []*plugin.Plugin
+}
+
+// New compiles the custom rules and loads the Rego policies and plugins
+// declared in the configuration
+func New(ctx context.Context, opts Options) (*Analyzer, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	if opts.Version == "" {
+		opts.Version = "dev"
+	}
+
+	if err := opts.Filter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	rules, err := policy.CompileAll(cfg.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("error loading rules: %w", err)
+	}
+
+	var regoPolicy *policy.RegoPolicy
+	if len(cfg.Rego.Paths) > 0 {
+		regoPolicy, err = policy.LoadRego(ctx, cfg.Rego)
+		if err != nil {
+			return nil, fmt.Errorf("error loading Rego policies: %w", err)
+		}
+	}
+
+	plugins, err := plugin.NewAll(cfg.Plugins)
+	if err != nil {
+		return nil, fmt.Errorf("error loading plugins: %w", err)
+	}
+
+	return &Analyzer{opts: opts, cfg: cfg, rules: rules, rego: regoPolicy, plugins: plugins}, nil
+}
+
+// Analyze reads a plan in the JSON format of `terraform show -json` and
+// analyzes it with a new Analyzer
+func Analyze(ctx context.Context, plan io.Reader, opts Options) (*Report, error) {
+	a, err := New(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.Analyze(ctx, plan)
+}
+
+// AnalyzeFile analyzes the plan file with the given name. Binary plans
+// saved with `terraform plan -out` are rendered as JSON with Options.Terraform
+// first.
+func (a *Analyzer) AnalyzeFile(ctx context.Context, filename string) (*Report, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %w", err)
+	}
+
+	if isBinaryPlan(data) {
+		data, err = showPlan(ctx, a.opts.Terraform, filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a.Analyze(ctx, bytes.NewReader(data))
+}
+
+// Analyze reads a plan in the JSON format of `terraform show -json` and
+// runs every analyzer on it. Failing plugins don't fail the analysis;
+// their errors are returned in Report.Errors.
+func (a *Analyzer) Analyze(ctx context.Context, plan io.Reader) (*Report, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(plan); err != nil {
+		return nil, fmt.Errorf("error reading plan: %w", err)
+	}
+	data := buf.Bytes()
+
+	summary, err := parser.ParsePlanJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if a.opts.ProviderVersions != nil {
+		summary.ApplyLockFile(a.opts.ProviderVersions)
+	}
+	full := summary
+	summary = summary.Filter(a.opts.Filter)
+
+	report := &Report{Summary: summary}
+	if !a.opts.SkipWarnings {
+		if err := a.runAnalyzers(ctx, report, data, full); err != nil {
+			return nil, err
+		}
+	}
+	report.Score = analyzer.ScorePlan(summary, report.AllWarnings(), a.cfg.RiskScore)
+
+	return report, nil
+}
+
+// runAnalyzers fills in the warnings of the report. full is the plan
+// before filtering.
+func (a *Analyzer) runAnalyzers(ctx context.Context, report *Report, planJSON []byte, full *parser.PlanSummary) error {
+	summary := report.Summary
+
+	report.Warnings = analyzer.AnalyzeChanges(summary, a.rules...)
+	report.PlanWarnings = analyzer.AnalyzePlan(summary, a.cfg.MassChange)
+	report.Moves = analyzer.DetectMoves(summary, analyzer.DefaultMoveSimilarity)
+
+	for _, m := range report.Moves {
+		report.Warnings = append(report.Warnings, m.Warning())
+	}
+
+	for _, shift := range analyzer.DetectIndexShifts(summary) {
+		report.Warnings = append(report.Warnings, shift.Warning())
+	}
+
+	report.Warnings = append(report.Warnings, analyzer.AnalyzeVersionChanges(summary, a.opts.Previous, analyzer.DefaultUpgradeCorrelation)...)
+
+	if a.rego != nil {
+		regoWarnings, err := a.rego.Evaluate(ctx, planJSON)
+		if err != nil {
+			return err
+		}
+		report.Warnings = append(report.Warnings, keptResources(regoWarnings, full, summary)...)
+	}
+
+	pluginWarnings, pluginErrors := plugin.RunAll(ctx, a.plugins, summary, a.opts.Version)
+	report.Warnings = append(report.Warnings, pluginWarnings...)
+	report.Errors = pluginErrors
+
+	return nil
+}
+
+// keptResources drops the warnings about resources left out by the filter.
+// Rego policies see the raw plan, including filtered resources. Warnings
+// about the plan or anything else than a resource of the plan are kept.
+func keptResources(warnings []analyzer.Warning, full, summary *parser.PlanSummary) []analyzer.Warning {
+	if summary.Total == nil {
+		return warnings
+	}
+
+	dropped := make(map[string]bool)
+	for _, c := range append(append([]parser.ResourceChange{}, full.Changes...), full.DataSources...) {
+		dropped[c.Address] = true
+	}
+	for _, c := range append(append([]parser.ResourceChange{}, summary.Changes...), summary.DataSources...) {
+		delete(dropped, c.Address)
+	}
+
+	result := make([]analyzer.Warning, 0, len(warnings))
+	for _, w := range warnings {
+		if !dropped[w.Resource] {
+			result = append(result, w)
+		}
+	}
+	return result
+}
@@ -0,0 +1,249 @@
+package infrasync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/config"
+	"github.com/kvizadsaderah/infrasync/pkg/formatter"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+func TestReport_ExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  parser.PlanSummary
+		warnings []analyzer.Warning
+		want     int
+	}{
+		{"no changes", parser.PlanSummary{NoChanges: 3}, nil, ExitNoChanges},
+		{"changes", parser.PlanSummary{ToUpdate: 1}, []analyzer.Warning{{Level: analyzer.RiskHigh}}, ExitChanges},
+		{"critical", parser.PlanSummary{ToDelete: 1}, []analyzer.Warning{{Level: analyzer.RiskCritical}}, ExitCritical},
+		{"critical without changes", parser.PlanSummary{}, []analyzer.Warning{{Level: analyzer.RiskCritical}}, ExitNoChanges},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := tt.summary
+			r := &Report{Summary: &summary, Warnings: tt.warnings}
+			if got := r.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_Notification(t *testing.T) {
+	r := &Report{
+		Summary:  &parser.PlanSummary{ToCreate: 1, ToReplace: 1},
+		Warnings: []analyzer.Warning{{Level: analyzer.RiskHigh}},
+	}
+	n := r.Notification(FormatOptions{Version: "1.2.3"})
+	if !n.Changes || !n.Deletes || n.Critical || n.ExitCode != ExitChanges || n.Version != "1.2.3" {
+		t.Errorf("Unexpected notification: %+v", n)
+	}
+
+	var buf bytes.Buffer
+	if err := n.Render(&buf, "json"); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Errorf("Render wrote invalid JSON: %s", buf.String())
+	}
+	if err := n.Render(&buf, "yaml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestAnalyze_SkipWarnings(t *testing.T) {
+	a, err := New(context.Background(), Options{SkipWarnings: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	report, err := a.AnalyzeFile(context.Background(), "testdata/plan.json")
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+
+	if len(report.AllWarnings()) != 0 {
+		t.Errorf("Expected no warnings, got %d", len(report.AllWarnings()))
+	}
+	if report.Score == nil || report.Score.Total == 0 {
+		t.Errorf("Expected the plan to be scored, got %+v", report.Score)
+	}
+	if got := report.ExitCode(); got != ExitChanges {
+		t.Errorf("ExitCode() = %d, want %d", got, ExitChanges)
+	}
+}
+
+func TestAnalyze_InvalidJSON(t *testing.T) {
+	_, err := Analyze(context.Background(), strings.NewReader("{"), Options{})
+	if err == nil {
+		t.Fatal("Expected an error for invalid JSON")
+	}
+}
+
+func TestReport_FormatJSON(t *testing.T) {
+	report := &Report{
+		Summary:  &parser.PlanSummary{ToDelete: 1},
+		Warnings: []analyzer.Warning{{Rule: "database-deletion", Level: analyzer.RiskCritical}},
+		Score:    &analyzer.PlanScore{Total: 10},
+	}
+
+	var buf bytes.Buffer
+	if err := report.Format(&buf, "json", FormatOptions{HideScore: true}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if out["exit_code"] != float64(ExitCritical) {
+		t.Errorf("exit_code = %v, want %d", out["exit_code"], ExitCritical)
+	}
+	if out["score"] != nil {
+		t.Errorf("score = %v, want null", out["score"])
+	}
+	if report.Score == nil {
+		t.Error("HideScore must not modify the report")
+	}
+}
+
+func TestReport_FormatJSONSensitive(t *testing.T) {
+	plan := `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_db_instance.main", "type": "aws_db_instance", "name": "main",
+     "change": {"actions": ["update"],
+       "before": {"identifier": "main", "password": "hunter2"},
+       "after": {"identifier": "main", "password": "hunter3"},
+       "before_sensitive": {"password": true}, "after_sensitive": {"password": true}}}
+  ]
+}`
+	report, err := Analyze(context.Background(), strings.NewReader(plan), Options{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Format(&buf, "json", FormatOptions{}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "hunter") {
+		t.Errorf("JSON output reveals a sensitive value:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"password": "(sensitive)"`) || !strings.Contains(buf.String(), `"identifier": "main"`) {
+		t.Errorf("expected the password masked and other values kept:\n%s", buf.String())
+	}
+	if report.Summary.Changes[0].After["password"] != "hunter3" {
+		t.Error("Format must not modify the report")
+	}
+
+	m := &MultiReport{Stacks: []StackReport{{PlanFile: PlanFile{Name: "db"}, Report: report}}}
+	buf.Reset()
+	if err := m.Format(&buf, "json", FormatOptions{}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "hunter") {
+		t.Errorf("combined JSON output reveals a sensitive value:\n%s", buf.String())
+	}
+}
+
+func TestReport_FormatTemplate(t *testing.T) {
+	tmpl, err := formatter.ParseTemplate("report", "{{ .Meta.Version }}: {{ len .Warnings }} warning, score {{ if .Score }}{{ .Score.Total }}{{ else }}hidden{{ end }}")
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+	report := &Report{
+		Summary:  &parser.PlanSummary{ToDelete: 1},
+		Warnings: []analyzer.Warning{{Rule: "database-deletion", Level: analyzer.RiskCritical}},
+		Score:    &analyzer.PlanScore{Total: 10},
+	}
+
+	var buf bytes.Buffer
+	if err := report.Format(&buf, "markdown", FormatOptions{Template: tmpl, Version: "1.0.0", HideScore: true}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if got, want := buf.String(), "1.0.0: 1 warning, score hidden"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
+
+func TestReport_FormatUnknown(t *testing.T) {
+	report := &Report{Summary: &parser.PlanSummary{}}
+	err := report.Format(&bytes.Buffer{}, "xml", FormatOptions{})
+	if err == nil || !strings.Contains(err.Error(), "cli, json, markdown") {
+		t.Errorf("Format(xml) error = %v, want list of supported formats", err)
+	}
+}
+
+func TestAnalyze_Filter(t *testing.T) {
+	policy := filepath.Join(t.TempDir(), "policy.rego")
+	err := os.WriteFile(policy, []byte(`package terraform
+
+deny contains result if {
+	some rc in input.resource_changes
+	"delete" in rc.change.actions
+	result := {"msg": "deleted", "resource": rc.address}
+}
+
+deny contains msg if {
+	count([rc | some rc in input.resource_changes; "delete" in rc.change.actions]) > 2
+	msg := "too many deletions"
+}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Rego.Paths = []string{policy}
+
+	a, err := New(context.Background(), Options{Config: cfg, Filter: parser.Filter{Types: []string{"aws_s3_bucket"}}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	report, err := a.AnalyzeFile(context.Background(), "testdata/plan.json")
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+
+	for _, c := range report.Summary.Changes {
+		if c.Type != "aws_s3_bucket" {
+			t.Errorf("Filtered summary contains %s", c.Address)
+		}
+	}
+	if report.Summary.Total == nil || report.Summary.Total.ToDelete != 3 {
+		t.Errorf("Total = %+v, want the counters of the whole plan", report.Summary.Total)
+	}
+	planWarning := false
+	for _, w := range report.AllWarnings() {
+		if w.Resource == "plan" {
+			planWarning = planWarning || w.Message == "too many deletions"
+			continue
+		}
+		if w.Resource != "" && !strings.HasPrefix(w.Resource, "aws_s3_bucket.") {
+			t.Errorf("Unexpected warning for a filtered resource: %+v", w)
+		}
+	}
+	// Plan-level findings don't name a resource and are kept
+	if !planWarning {
+		t.Error("Expected the plan-level Rego warning to be kept")
+	}
+	if report.ExitCode() != ExitCritical {
+		t.Errorf("ExitCode() = %d, want %d", report.ExitCode(), ExitCritical)
+	}
+}
+
+func TestNew_InvalidFilter(t *testing.T) {
+	_, err := New(context.Background(), Options{Filter: parser.Filter{Actions: []string{"remove"}}})
+	if err == nil {
+		t.Fatal("Expected an error for an unknown action")
+	}
+}
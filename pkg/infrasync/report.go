@@ -0,0 +1,245 @@
+package infrasync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/formatter"
+	"github.com/kvizadsaderah/infrasync/pkg/notify"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// Exit codes returned by Report.ExitCode, as used by the command line tool
+const (
+	ExitNoChanges = 0 // the plan changes nothing
+	ExitChanges   = 1 // the plan has changes
+	ExitCritical  = 2 // the plan has changes and critical warnings
+)
+
+// Report is the result of analyzing one plan
+type Report struct {
+	Summary      *parser.PlanSummary       `json:"summary"`
+	PlanWarnings []analyzer.Warning        `json:"plan_warnings"` // plan-wide anomalies such as mass deletions
+	Warnings     []analyzer.Warning        `json:"warnings"`      // resource warnings
+	Moves        []analyzer.MoveSuggestion `json:"moves"`         // suggested moved blocks
+	Score        *analyzer.PlanScore       `json:"score"`
+
+	// Errors are non-fatal analyzer failures, such as plugins that crashed
+	// or timed out. The warnings of the remaining analyzers are complete.
+	Errors []error `json:"-"`
+}
+
+// AllWarnings returns the plan-wide and resource warnings together
+func (r *Report) AllWarnings() []analyzer.Warning {
+	return append(append([]analyzer.Warning{}, r.PlanWarnings...), r.Warnings...)
+}
+
+// HasChanges reports whether the plan creates, updates, replaces or
+// deletes anything
+func (r *Report) HasChanges() bool {
+	s := r.Summary
+	return s.ToCreate > 0 || s.ToUpdate > 0 || s.ToDelete > 0 || s.ToReplace > 0
+}
+
+// HasDeletes reports whether the plan deletes or replaces resources
+func (r *Report) HasDeletes() bool {
+	return r.Summary.ToDelete > 0 || r.Summary.ToReplace > 0
+}
+
+// HasCritical reports whether any warning is critical
+func (r *Report) HasCritical() bool {
+	for _, w := range r.AllWarnings() {
+		if w.Level == analyzer.RiskCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns ExitNoChanges, ExitChanges or ExitCritical
+func (r *Report) ExitCode() int {
+	if !r.HasChanges() {
+		return ExitNoChanges
+	}
+	if r.HasCritical() {
+		return ExitCritical
+	}
+	return ExitChanges
+}
+
+// Notification returns the report as delivered to webhooks, rendered in
+// the format of each webhook with opts
+func (r *Report) Notification(opts FormatOptions) notify.Report {
+	return notify.Report{
+		Render:   func(w io.Writer, format string) error { return r.Format(w, format, opts) },
+		Changes:  r.HasChanges(),
+		Deletes:  r.HasDeletes(),
+		Critical: r.HasCritical(),
+		ExitCode: r.ExitCode(),
+		Version:  opts.Version,
+	}
+}
+
+// FormatOptions controls how much detail a format renders
+type FormatOptions struct {
+	ShowUnchanged bool // list resources without changes
+	Verbose       bool // show all attributes
+	Compact       bool // summary only, without the resource list
+	HideScore     bool // leave out the risk score
+
+	// Order controls the order of attributes within a resource. The zero
+	// value sorts them by name.
+	Order formatter.AttributeOrder
+
+	// GroupBy groups the resource list by module, provider or type. The
+	// zero value groups by action.
+	GroupBy formatter.GroupBy
+
+	// MaxLength limits the length of the markdown format, e.g. to a
+	// formatter.CommentLimit; zero means no limit. Longer reports are split
+	// into parts or truncated as selected by Overflow, with ArtifactURL
+	// pointing to the full report.
+	MaxLength   int
+	Overflow    formatter.Overflow
+	ArtifactURL string
+
+	// Template renders the markdown format with a custom template, see
+	// formatter.ParseTemplateFile. Version is shown by templates as
+	// .Meta.Version.
+	Template *template.Template
+	Version  string
+
+	// Color applies to the cli and terraform formats. The default colors terminals
+	// unless NO_COLOR is set.
+	Color formatter.ColorMode
+}
+
+type formatFunc func(w io.Writer, r *Report, opts FormatOptions) error
+
+var formats = map[string]formatFunc{
+	"cli":       formatCLI,
+	"markdown":  formatMarkdown,
+	"slack":     formatSlack,
+	"teams":     formatTeams,
+	"json":      formatJSON,
+	"terraform": formatTerraform,
+}
+
+// Formats returns the names accepted by Report.Format
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Format renders the report in the named format
+func (r *Report) Format(w io.Writer, format string, opts FormatOptions) error {
+	f, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return f(w, r, opts)
+}
+
+func formatCLI(w io.Writer, r *Report, opts FormatOptions) error {
+	f := formatter.NewCLIFormatter(opts.ShowUnchanged, opts.Verbose)
+	f.Color = opts.Color
+	f.Order = opts.Order
+	f.GroupBy = opts.GroupBy
+	f.Analysis = r.analysis(opts)
+	return f.Format(w, r.Summary)
+}
+
+func formatMarkdown(w io.Writer, r *Report, opts FormatOptions) error {
+	if opts.Template != nil {
+		f := opts.templateFormatter()
+		f.Analysis = r.analysis(opts)
+		return f.Format(w, r.Summary)
+	}
+
+	f := opts.markdownFormatter()
+	f.Analysis = r.analysis(opts)
+	return f.Format(w, r.Summary)
+}
+
+func (opts FormatOptions) markdownFormatter() *formatter.MarkdownFormatter {
+	f := formatter.NewMarkdownFormatter(!opts.Compact, opts.Compact, opts.ShowUnchanged)
+	f.Order = opts.Order
+	f.GroupBy = opts.GroupBy
+	f.MaxLength = opts.MaxLength
+	f.Overflow = opts.Overflow
+	f.ArtifactURL = opts.ArtifactURL
+	return f
+}
+
+func (opts FormatOptions) templateFormatter() *formatter.TemplateFormatter {
+	return &formatter.TemplateFormatter{
+		Template:      opts.Template,
+		ShowDetails:   !opts.Compact,
+		CompactMode:   opts.Compact,
+		ShowUnchanged: opts.ShowUnchanged,
+		Order:         opts.Order,
+		GroupBy:       opts.GroupBy,
+		Meta:          formatter.TemplateMeta{Version: opts.Version},
+		MaxLength:     opts.MaxLength,
+		Overflow:      opts.Overflow,
+		ArtifactURL:   opts.ArtifactURL,
+	}
+}
+
+func formatSlack(w io.Writer, r *Report, opts FormatOptions) error {
+	f := &formatter.SlackFormatter{ArtifactURL: opts.ArtifactURL, Analysis: r.analysis(opts)}
+	return f.Format(w, r.Summary)
+}
+
+func formatTeams(w io.Writer, r *Report, opts FormatOptions) error {
+	f := &formatter.TeamsFormatter{ArtifactURL: opts.ArtifactURL, Analysis: r.analysis(opts)}
+	return f.Format(w, r.Summary)
+}
+
+func formatTerraform(w io.Writer, r *Report, opts FormatOptions) error {
+	f := &formatter.TerraformFormatter{Color: opts.Color, Order: opts.Order, Analysis: r.analysis(opts)}
+	return f.Format(w, r.Summary)
+}
+
+// jsonReport returns a copy of the report for JSON output, with sensitive
+// values masked
+func (r *Report) jsonReport(opts FormatOptions) *Report {
+	copied := *r
+	copied.Summary = r.Summary.Masked()
+	if opts.HideScore {
+		copied.Score = nil
+	}
+	return &copied
+}
+
+func (r *Report) analysis(opts FormatOptions) formatter.Analysis {
+	a := formatter.Analysis{
+		PlanWarnings: r.PlanWarnings,
+		Warnings:     r.Warnings,
+		Moves:        r.Moves,
+	}
+	if !opts.HideScore {
+		a.Score = r.Score
+	}
+	return a
+}
+
+func formatJSON(w io.Writer, r *Report, opts FormatOptions) error {
+	out := struct {
+		*Report
+		ExitCode int `json:"exit_code"`
+	}{Report: r.jsonReport(opts), ExitCode: r.ExitCode()}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,213 @@
+package infrasync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/kvizadsaderah/infrasync/pkg/formatter"
+	"github.com/kvizadsaderah/infrasync/pkg/notify"
+)
+
+// PlanFile is a plan JSON file labeled with the stack it belongs to
+type PlanFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// PlanFiles resolves command line arguments into labeled plan files.
+// Arguments are paths, glob patterns or name=path pairs. Stacks without an
+// explicit name are named after the directory of the plan, or after the
+// file when it is in the working directory.
+func PlanFiles(args []string) ([]PlanFile, error) {
+	files := make([]PlanFile, 0, len(args))
+	for _, arg := range args {
+		name, path := "", arg
+		if i := strings.Index(arg, "="); i > 0 && !strings.ContainsAny(arg[:i], `/\`) {
+			name, path = arg[:i], arg[i+1:]
+		}
+
+		paths := []string{path}
+		if strings.ContainsAny(path, "*?[") {
+			matches, err := filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", path, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no plan files match %q", path)
+			}
+			paths = matches
+		}
+
+		for _, p := range paths {
+			n := name
+			if n == "" || len(paths) > 1 {
+				n = stackName(p)
+			}
+			files = append(files, PlanFile{Name: n, Path: p})
+		}
+	}
+
+	return uniqueNames(files), nil
+}
+
+// stackName derives a stack name from the path of its plan
+func stackName(path string) string {
+	dir := filepath.Base(filepath.Dir(filepath.Clean(path)))
+	if dir == "." || dir == string(filepath.Separator) {
+		return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return dir
+}
+
+// uniqueNames qualifies duplicate derived names with the plan path
+func uniqueNames(files []PlanFile) []PlanFile {
+	counts := make(map[string]int)
+	for _, f := range files {
+		counts[f.Name]++
+	}
+	for i, f := range files {
+		if counts[f.Name] > 1 {
+			files[i].Name = filepath.ToSlash(strings.TrimSuffix(filepath.Clean(f.Path), filepath.Ext(f.Path)))
+		}
+	}
+	return files
+}
+
+// StackReport is the report of one stack
+type StackReport struct {
+	PlanFile
+	*Report
+}
+
+// MultiReport bundles the reports of several stacks, in input order
+type MultiReport struct {
+	Stacks []StackReport
+}
+
+// AnalyzeFiles analyzes the plans concurrently. The first failing plan
+// fails the whole analysis.
+func (a *Analyzer) AnalyzeFiles(ctx context.Context, files []PlanFile) (*MultiReport, error) {
+	reports := make([]StackReport, len(files))
+	errs := make([]error, len(files))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, file := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			report, err := a.AnalyzeFile(ctx, file.Path)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", file.Name, err)
+				return
+			}
+			reports[i] = StackReport{PlanFile: file, Report: report}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &MultiReport{Stacks: reports}, nil
+}
+
+// Errors returns the non-fatal analyzer failures of all stacks
+func (m *MultiReport) Errors() []error {
+	errs := make([]error, 0)
+	for _, s := range m.Stacks {
+		for _, err := range s.Errors {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+		}
+	}
+	return errs
+}
+
+// ExitCode returns the most severe exit code of the stacks
+func (m *MultiReport) ExitCode() int {
+	code := ExitNoChanges
+	for _, s := range m.Stacks {
+		code = max(code, s.ExitCode())
+	}
+	return code
+}
+
+// Notification returns the combined report as delivered to webhooks,
+// triggered by the changes and warnings of any stack
+func (m *MultiReport) Notification(opts FormatOptions) notify.Report {
+	n := notify.Report{
+		Render:   func(w io.Writer, format string) error { return m.Format(w, format, opts) },
+		ExitCode: m.ExitCode(),
+		Version:  opts.Version,
+	}
+	for _, s := range m.Stacks {
+		n.Changes = n.Changes || s.HasChanges()
+		n.Deletes = n.Deletes || s.HasDeletes()
+		n.Critical = n.Critical || s.HasCritical()
+	}
+	return n
+}
+
+// Format renders the combined report in the named format
+func (m *MultiReport) Format(w io.Writer, format string, opts FormatOptions) error {
+	stacks := make([]formatter.Stack, 0, len(m.Stacks))
+	for _, s := range m.Stacks {
+		stacks = append(stacks, formatter.Stack{Name: s.Name, Summary: s.Summary, Analysis: s.analysis(opts)})
+	}
+
+	switch format {
+	case "cli":
+		f := formatter.NewCLIFormatter(opts.ShowUnchanged, opts.Verbose)
+		f.Color = opts.Color
+		f.Order = opts.Order
+		f.GroupBy = opts.GroupBy
+		return f.FormatStacks(w, stacks)
+	case "markdown":
+		if opts.Template != nil {
+			return opts.templateFormatter().FormatStacks(w, stacks)
+		}
+		return opts.markdownFormatter().FormatStacks(w, stacks)
+	case "terraform":
+		f := &formatter.TerraformFormatter{Color: opts.Color, Order: opts.Order}
+		return f.FormatStacks(w, stacks)
+	case "slack":
+		return (&formatter.SlackFormatter{ArtifactURL: opts.ArtifactURL}).FormatStacks(w, stacks)
+	case "teams":
+		return (&formatter.TeamsFormatter{ArtifactURL: opts.ArtifactURL}).FormatStacks(w, stacks)
+	case "json":
+		return m.formatJSON(w, opts)
+	}
+	return fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(Formats(), ", "))
+}
+
+func (m *MultiReport) formatJSON(w io.Writer, opts FormatOptions) error {
+	type stackJSON struct {
+		PlanFile
+		*Report
+		ExitCode int `json:"exit_code"`
+	}
+	out := struct {
+		Stacks   []stackJSON `json:"stacks"`
+		ExitCode int         `json:"exit_code"`
+	}{Stacks: make([]stackJSON, 0, len(m.Stacks)), ExitCode: m.ExitCode()}
+
+	for _, s := range m.Stacks {
+		out.Stacks = append(out.Stacks, stackJSON{PlanFile: s.PlanFile, Report: s.jsonReport(opts), ExitCode: s.ExitCode()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
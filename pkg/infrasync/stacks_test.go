@@ -0,0 +1,141 @@
+package infrasync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []PlanFile
+	}{
+		{
+			name: "derived from directory",
+			args: []string{"stacks/network/tfplan.json", "data/plan.json"},
+			want: []PlanFile{{"network", "stacks/network/tfplan.json"}, {"data", "data/plan.json"}},
+		},
+		{
+			name: "derived from file in working directory",
+			args: []string{"network.json"},
+			want: []PlanFile{{"network", "network.json"}},
+		},
+		{
+			name: "explicit names",
+			args: []string{"net=stacks/network/tfplan.json", "./a=b.json"},
+			want: []PlanFile{{"net", "stacks/network/tfplan.json"}, {"a=b", "./a=b.json"}},
+		},
+		{
+			name: "duplicates use the path",
+			args: []string{"prod/app/tfplan.json", "staging/app/tfplan.json"},
+			want: []PlanFile{{"prod/app/tfplan", "prod/app/tfplan.json"}, {"staging/app/tfplan", "staging/app/tfplan.json"}},
+		},
+		{
+			name: "glob",
+			args: []string{"testdata/stacks/*/tfplan.json"},
+			want: []PlanFile{{"apps", "testdata/stacks/apps/tfplan.json"}, {"network", "testdata/stacks/network/tfplan.json"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanFiles(tt.args)
+			if err != nil {
+				t.Fatalf("PlanFiles failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanFiles_NoMatch(t *testing.T) {
+	if _, err := PlanFiles([]string{"testdata/missing/*.json"}); err == nil {
+		t.Error("Expected an error for a pattern without matches")
+	}
+}
+
+func TestAnalyzeFiles(t *testing.T) {
+	a, err := New(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	files := []PlanFile{
+		{"data", "testdata/plan.json"},
+		{"network", "testdata/stacks/network/tfplan.json"},
+		{"apps", "testdata/stacks/apps/tfplan.json"},
+	}
+	m, err := a.AnalyzeFiles(context.Background(), files)
+	if err != nil {
+		t.Fatalf("AnalyzeFiles failed: %v", err)
+	}
+
+	codes := make([]int, 0, len(m.Stacks))
+	for i, s := range m.Stacks {
+		if s.Name != files[i].Name {
+			t.Errorf("Stack %d = %s, want %s", i, s.Name, files[i].Name)
+		}
+		codes = append(codes, s.ExitCode())
+	}
+	if want := []int{ExitCritical, ExitChanges, ExitNoChanges}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("Stack exit codes = %v, want %v", codes, want)
+	}
+	if got := m.ExitCode(); got != ExitCritical {
+		t.Errorf("ExitCode() = %d, want %d", got, ExitCritical)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Format(&buf, "json", FormatOptions{}); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var out struct {
+		Stacks []struct {
+			Name     string `json:"name"`
+			ExitCode int    `json:"exit_code"`
+		} `json:"stacks"`
+		ExitCode int `json:"exit_code"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(out.Stacks) != 3 || out.Stacks[1].Name != "network" || out.ExitCode != ExitCritical {
+		t.Errorf("Unexpected JSON output: %s", buf.String())
+	}
+
+	n := m.Notification(FormatOptions{})
+	if !n.Changes || !n.Deletes || !n.Critical || n.ExitCode != ExitCritical {
+		t.Errorf("Unexpected notification: %+v", n)
+	}
+
+	for _, format := range Formats() {
+		var buf bytes.Buffer
+		if err := m.Format(&buf, format, FormatOptions{}); err != nil {
+			t.Errorf("Format(%s) failed: %v", format, err)
+		}
+		if (format == "slack" || format == "teams") && !json.Valid(buf.Bytes()) {
+			t.Errorf("Format(%s) wrote invalid JSON: %s", format, buf.String())
+		}
+	}
+}
+
+func TestAnalyzeFiles_Error(t *testing.T) {
+	a, err := New(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	_, err = a.AnalyzeFiles(context.Background(), []PlanFile{
+		{"data", "testdata/plan.json"},
+		{"broken", "testdata/missing.json"},
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "broken:") {
+		t.Errorf("AnalyzeFiles() error = %v, want error labeled with the stack", err)
+	}
+}
@@ -0,0 +1,179 @@
+package infrasync
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// terragruntCache is the directory Terragrunt copies each unit into before
+// running Terraform
+const terragruntCache = ".terragrunt-cache"
+
+// DiscoverPlans walks root for plan files, in JSON or binary format, as
+// left behind by `terragrunt run-all plan`. Each Terragrunt unit yields
+// one plan, named after the unit directory relative to root. A unit's plan
+// outside the cache wins over copies inside .terragrunt-cache; among
+// cached copies, the most recent one is used.
+func DiscoverPlans(root string) ([]PlanFile, error) {
+	units := make(map[string]discoveredPlan)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", ".terraform":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		binary, ok := planFormat(path)
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		unit, cached := unitDir(path)
+		p := discoveredPlan{path: path, cached: cached, binary: binary, modTime: info.ModTime()}
+		if current, exists := units[unit]; !exists || p.betterThan(current) {
+			units[unit] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error discovering plans: %w", err)
+	}
+
+	files := make([]PlanFile, 0, len(units))
+	for unit, p := range units {
+		files = append(files, PlanFile{Name: unitName(root, unit), Path: p.path})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	return files, nil
+}
+
+// unitDir returns the Terragrunt unit a plan file belongs to: the
+// directory holding the outermost .terragrunt-cache on its path, or the
+// directory of the file when it isn't cached
+func unitDir(path string) (string, bool) {
+	dir := filepath.Dir(path)
+	parts := strings.Split(filepath.ToSlash(dir), "/")
+	for i, part := range parts {
+		if part == terragruntCache {
+			return filepath.FromSlash(strings.Join(parts[:i], "/")), true
+		}
+	}
+	return dir, false
+}
+
+// unitName names a unit after its path relative to root
+func unitName(root, unit string) string {
+	rel, err := filepath.Rel(root, unit)
+	if err != nil || rel == "." {
+		abs, err := filepath.Abs(unit)
+		if err != nil {
+			return filepath.Base(unit)
+		}
+		return filepath.Base(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// discoveredPlan is a plan file found for a unit
+type discoveredPlan struct {
+	path    string
+	cached  bool
+	binary  bool
+	modTime time.Time
+}
+
+// betterThan reports whether p is a better pick for its unit than other:
+// uncached over cached, JSON over binary, then the most recent
+func (p discoveredPlan) betterThan(other discoveredPlan) bool {
+	if p.cached != other.cached {
+		return !p.cached
+	}
+	if p.binary != other.binary {
+		return !p.binary
+	}
+	return p.modTime.After(other.modTime)
+}
+
+// planFormat reports whether the file is a Terraform plan, and whether it
+// is a binary plan rather than JSON
+func planFormat(path string) (binary, ok bool) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, false
+		}
+		var plan struct {
+			FormatVersion   string          `json:"format_version"`
+			PlannedValues   json.RawMessage `json:"planned_values"`
+			ResourceChanges json.RawMessage `json:"resource_changes"`
+		}
+		if json.Unmarshal(data, &plan) != nil {
+			return false, false
+		}
+		return false, plan.FormatVersion != "" && (plan.PlannedValues != nil || plan.ResourceChanges != nil)
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return false, false
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name == "tfplan" {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// isBinaryPlan reports whether data looks like a plan file saved with
+// `terraform plan -out`, which is a zip archive
+func isBinaryPlan(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// showPlan renders a binary plan as JSON. Terraform runs in the directory
+// of the plan, which has to be the initialized working directory the plan
+// was created in, as it is for plans in .terragrunt-cache.
+func showPlan(ctx context.Context, terraform, path string) ([]byte, error) {
+	if terraform == "" {
+		terraform = "terraform"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, terraform, "show", "-json", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("error rendering binary plan %s: %w: %s", path, err, msg)
+		}
+		return nil, fmt.Errorf("error rendering binary plan %s: %w", path, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,157 @@
+package infrasync
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as terraform when launched by the tests
+func TestMain(m *testing.M) {
+	if plan := os.Getenv("INFRASYNC_TEST_TERRAFORM"); plan != "" {
+		os.Exit(runTestTerraform(plan))
+	}
+	os.Exit(m.Run())
+}
+
+func runTestTerraform(plan string) int {
+	if len(os.Args) != 4 || os.Args[1] != "show" || os.Args[2] != "-json" {
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q\n", os.Args[1:])
+		return 1
+	}
+	if _, err := os.Stat(os.Args[3]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data, err := os.ReadFile(plan)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	_, _ = os.Stdout.Write(data)
+	return 0
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeBinaryPlan(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("tfplan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverPlans(t *testing.T) {
+	plan, err := os.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	// Cached copies: the most recent one wins, provider caches are ignored
+	writeFile(t, filepath.Join(root, "prod/vpc/terragrunt.hcl"), nil, older)
+	writeFile(t, filepath.Join(root, "prod/vpc/.terragrunt-cache/old/mod/tfplan.json"), plan, older)
+	writeFile(t, filepath.Join(root, "prod/vpc/.terragrunt-cache/new/mod/tfplan.json"), plan, newer)
+	writeFile(t, filepath.Join(root, "prod/vpc/.terragrunt-cache/new/mod/.terraform/tfplan.json"), plan, newer)
+
+	// A plan next to the unit wins over the cache
+	writeFile(t, filepath.Join(root, "prod/db/tfplan.json"), plan, older)
+	writeFile(t, filepath.Join(root, "prod/db/.terragrunt-cache/h/mod/tfplan.json"), plan, newer)
+
+	// Binary plans are found by content
+	writeBinaryPlan(t, filepath.Join(root, "prod/app/.terragrunt-cache/h/mod/tfplan"))
+
+	// Other JSON files are skipped
+	writeFile(t, filepath.Join(root, "prod/config.json"), []byte(`{"format_version": "1.0"}`), newer)
+	writeFile(t, filepath.Join(root, "prod/broken.json"), []byte(`{`), newer)
+
+	got, err := DiscoverPlans(root)
+	if err != nil {
+		t.Fatalf("DiscoverPlans failed: %v", err)
+	}
+	want := []PlanFile{
+		{"prod/app", filepath.Join(root, "prod/app/.terragrunt-cache/h/mod/tfplan")},
+		{"prod/db", filepath.Join(root, "prod/db/tfplan.json")},
+		{"prod/vpc", filepath.Join(root, "prod/vpc/.terragrunt-cache/new/mod/tfplan.json")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverPlans() = %v, want %v", got, want)
+	}
+}
+
+func TestDiscoverPlans_RootUnit(t *testing.T) {
+	plan, err := os.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(t.TempDir(), "vpc")
+	writeFile(t, filepath.Join(root, ".terragrunt-cache/h/tfplan.json"), plan, time.Now())
+
+	got, err := DiscoverPlans(root)
+	if err != nil {
+		t.Fatalf("DiscoverPlans failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "vpc" {
+		t.Errorf("DiscoverPlans() = %v, want a single vpc unit", got)
+	}
+}
+
+func TestAnalyzeFile_BinaryPlan(t *testing.T) {
+	planJSON, err := filepath.Abs("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tfplan")
+	writeBinaryPlan(t, path)
+
+	a, err := New(context.Background(), Options{SkipWarnings: true, Terraform: os.Args[0]})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	t.Setenv("INFRASYNC_TEST_TERRAFORM", planJSON)
+	report, err := a.AnalyzeFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if len(report.Summary.Changes) == 0 {
+		t.Error("Expected the changes of the rendered plan")
+	}
+
+	t.Setenv("INFRASYNC_TEST_TERRAFORM", filepath.Join(t.TempDir(), "missing.json"))
+	_, err = a.AnalyzeFile(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "error rendering binary plan") {
+		t.Errorf("AnalyzeFile() error = %v, want a rendering error", err)
+	}
+}
@@ -0,0 +1,371 @@
+// Package notify delivers reports to webhooks, such as Slack or Teams
+// incoming webhooks or any HTTP endpoint accepting JSON
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Defaults for webhooks configured without them
+const (
+	DefaultFormat          = "json"
+	DefaultAttempts        = 3
+	DefaultBackoff         = time.Second
+	DefaultTimeout         = 10 * time.Second
+	DefaultSignatureHeader = "X-InfraSync-Signature"
+)
+
+// maxBackoff bounds the wait between attempts, including waits asked for
+// with Retry-After
+const maxBackoff = time.Minute
+
+// Trigger selects the reports a webhook receives
+type Trigger string
+
+const (
+	TriggerAlways   Trigger = "always"   // every report, the default
+	TriggerChanges  Trigger = "changes"  // plans changing anything
+	TriggerDeletes  Trigger = "deletes"  // plans deleting or replacing resources
+	TriggerCritical Trigger = "critical" // reports with critical warnings
+)
+
+// Config declares a webhook. The URL, header values and secret are
+// templates, see TemplateData.
+type Config struct {
+	Name            string            `yaml:"name"`
+	URL             string            `yaml:"url"`
+	Format          string            `yaml:"format"` // report format posted, json by default
+	Headers         map[string]string `yaml:"headers"`
+	Secret          string            `yaml:"secret"`           // signs payloads with HMAC-SHA256 when set
+	SignatureHeader string            `yaml:"signature_header"` // carries the signature
+	When            []Trigger         `yaml:"when"`             // any of them triggers the webhook
+	Attempts        int               `yaml:"attempts"`         // including the first
+	Backoff         time.Duration     `yaml:"backoff"`          // before the first retry, doubling after each
+	Timeout         time.Duration     `yaml:"timeout"`          // per attempt
+}
+
+// Report is a report to deliver: a function rendering it in the format
+// of each webhook, e.g. infrasync.Report.Format, and the facts triggers
+// and templates test
+type Report struct {
+	Render   func(w io.Writer, format string) error
+	Changes  bool // anything is created, updated, replaced or deleted
+	Deletes  bool // resources are deleted or replaced
+	Critical bool // any warning is critical
+	ExitCode int
+	Version  string // InfraSync version, sent as the User-Agent
+}
+
+// TemplateData is available to the URL, header and secret templates,
+// together with the env function returning environment variables:
+//
+//	Authorization: Bearer {{ env "WEBHOOK_TOKEN" }}
+type TemplateData struct {
+	Name   string // of the webhook
+	Format string
+	Report
+}
+
+// Webhook is a configured webhook
+type Webhook struct {
+	config  Config
+	url     *template.Template
+	secret  *template.Template
+	headers map[string]*template.Template
+	client  *http.Client
+}
+
+// New validates a webhook configuration and parses its templates
+func New(cfg Config) (*Webhook, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("webhook without name")
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook %s: url is required", cfg.Name)
+	}
+	for _, t := range cfg.When {
+		switch t {
+		case TriggerAlways, TriggerChanges, TriggerDeletes, TriggerCritical:
+		default:
+			return nil, fmt.Errorf("webhook %s: unknown trigger %q (supported: always, changes, deletes, critical)", cfg.Name, t)
+		}
+	}
+	if cfg.Format == "" {
+		cfg.Format = DefaultFormat
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = DefaultSignatureHeader
+	}
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = DefaultAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	h := &Webhook{config: cfg, headers: make(map[string]*template.Template), client: &http.Client{}}
+	var err error
+	if h.url, err = parseTemplate(cfg.Name, "url", cfg.URL); err != nil {
+		return nil, err
+	}
+	if cfg.Secret != "" {
+		if h.secret, err = parseTemplate(cfg.Name, "secret", cfg.Secret); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range cfg.Headers {
+		if h.headers[name], err = parseTemplate(cfg.Name, "header "+name, value); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// NewAll validates every webhook configuration
+func NewAll(configs []Config) ([]*Webhook, error) {
+	hooks := make([]*Webhook, 0, len(configs))
+	for _, cfg := range configs {
+		h, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, nil
+}
+
+func parseTemplate(webhook, name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"env": os.Getenv}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %s: error parsing %s: %w", webhook, name, err)
+	}
+	return t, nil
+}
+
+// Name returns the webhook name
+func (h *Webhook) Name() string {
+	return h.config.Name
+}
+
+// Format returns the report format posted by the webhook
+func (h *Webhook) Format() string {
+	return h.config.Format
+}
+
+// Triggered reports whether the webhook receives the report
+func (h *Webhook) Triggered(r Report) bool {
+	if len(h.config.When) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(h.config.When, func(t Trigger) bool {
+		switch t {
+		case TriggerAlways:
+			return true
+		case TriggerChanges:
+			return r.Changes
+		case TriggerDeletes:
+			return r.Deletes
+		case TriggerCritical:
+			return r.Critical
+		}
+		return false
+	})
+}
+
+// Send renders the report in the webhook's format and posts it. Network
+// errors, timeouts and 429 and 5xx responses are retried with exponential
+// backoff; other responses fail at once. It returns the number of
+// attempts made.
+func (h *Webhook) Send(ctx context.Context, r Report) (int, error) {
+	var body bytes.Buffer
+	if err := r.Render(&body, h.config.Format); err != nil {
+		return 0, fmt.Errorf("webhook %s: error rendering %s: %w", h.config.Name, h.config.Format, err)
+	}
+
+	data := TemplateData{Name: h.config.Name, Format: h.config.Format, Report: r}
+	url, err := execute(h.url, data)
+	if err != nil {
+		return 0, fmt.Errorf("webhook %s: error rendering url: %w", h.config.Name, err)
+	}
+	if url == "" {
+		return 0, fmt.Errorf("webhook %s: url is empty", h.config.Name)
+	}
+	headers := make(http.Header)
+	for name, t := range h.headers {
+		value, err := execute(t, data)
+		if err != nil {
+			return 0, fmt.Errorf("webhook %s: error rendering header %s: %w", h.config.Name, name, err)
+		}
+		headers.Set(name, value)
+	}
+	if h.secret != nil {
+		secret, err := execute(h.secret, data)
+		if err != nil {
+			return 0, fmt.Errorf("webhook %s: error rendering secret: %w", h.config.Name, err)
+		}
+		if secret == "" {
+			return 0, fmt.Errorf("webhook %s: secret is empty", h.config.Name)
+		}
+		headers.Set(h.config.SignatureHeader, Sign([]byte(secret), body.Bytes()))
+	}
+	if headers.Get("Content-Type") == "" {
+		headers.Set("Content-Type", contentType(h.config.Format))
+	}
+	if headers.Get("User-Agent") == "" {
+		headers.Set("User-Agent", "InfraSync/"+r.Version)
+	}
+
+	wait := h.config.Backoff
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := h.post(ctx, url, headers, body.Bytes())
+		if err == nil {
+			return attempt, nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt == h.config.Attempts || ctx.Err() != nil {
+			return attempt, fmt.Errorf("webhook %s: %w", h.config.Name, err)
+		}
+
+		delay := min(max(wait, retryAfter), maxBackoff)
+		select {
+		case <-ctx.Done():
+			return attempt, fmt.Errorf("webhook %s: %w", h.config.Name, ctx.Err())
+		case <-time.After(delay):
+		}
+		wait *= 2
+	}
+}
+
+// permanentError is a failure not worth retrying
+type permanentError struct {
+	status string
+	body   string
+}
+
+func (e *permanentError) Error() string {
+	return responseError(e.status, e.body)
+}
+
+// post makes one attempt, returning the wait asked for by the server
+func (h *Webhook) post(ctx context.Context, target string, headers http.Header, body []byte) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, &permanentError{status: "invalid url: " + redact(err).Error()}
+	}
+	req.Header = headers.Clone()
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return 0, fmt.Errorf("timed out after %s", h.config.Timeout)
+		}
+		return 0, redact(err)
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return parseRetryAfter(resp.Header.Get("Retry-After")), errors.New(responseError(resp.Status, string(snippet)))
+	}
+	return 0, &permanentError{status: resp.Status, body: string(snippet)}
+}
+
+// redact drops the request URL from an error, as webhook URLs often
+// embed a token
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func responseError(status, body string) string {
+	if body = strings.TrimSpace(body); body != "" {
+		return status + ": " + body
+	}
+	return status
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func execute(t *template.Template, data TemplateData) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func contentType(format string) string {
+	switch format {
+	case "json", "slack", "teams":
+		return "application/json"
+	case "markdown":
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Sign returns the signature of a payload as sent in the signature
+// header: sha256= followed by the hex-encoded HMAC-SHA256 of the body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Result is the outcome of delivering a report to a webhook
+type Result struct {
+	Webhook  string
+	Skipped  bool // not triggered by the report
+	Attempts int
+	Err      error
+}
+
+// Notify delivers the report to every triggered webhook in order. A
+// failing webhook doesn't stop the others.
+func Notify(ctx context.Context, hooks []*Webhook, r Report) []Result {
+	results := make([]Result, 0, len(hooks))
+	for _, h := range hooks {
+		if !h.Triggered(r) {
+			results = append(results, Result{Webhook: h.Name(), Skipped: true})
+			continue
+		}
+		attempts, err := h.Send(ctx, r)
+		results = append(results, Result{Webhook: h.Name(), Attempts: attempts, Err: err})
+	}
+	return results
+}
@@ -0,0 +1,336 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testReport() Report {
+	return Report{
+		Render: func(w io.Writer, format string) error {
+			_, err := fmt.Fprintf(w, `{"format": %q}`, format)
+			return err
+		},
+		Changes:  true,
+		Deletes:  true,
+		Critical: true,
+		ExitCode: 2,
+		Version:  "1.0.0",
+	}
+}
+
+func testWebhook(t *testing.T, cfg Config) *Webhook {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = "test"
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = time.Millisecond
+	}
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return h
+}
+
+func TestWebhook_Send(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_TOKEN", "s3cret")
+	t.Setenv("TEST_WEBHOOK_SECRET", "signing-key")
+
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	h := testWebhook(t, Config{
+		Name:   "chat",
+		URL:    server.URL + "/hooks/{{ .Name }}",
+		Format: "slack",
+		Headers: map[string]string{
+			"Authorization": `Bearer {{ env "TEST_WEBHOOK_TOKEN" }}`,
+			"X-Plan-Status": "{{ if .Critical }}critical{{ else }}ok{{ end }} ({{ .ExitCode }})",
+		},
+		Secret: `{{ env "TEST_WEBHOOK_SECRET" }}`,
+	})
+	attempts, err := h.Send(context.Background(), testReport())
+	if err != nil || attempts != 1 {
+		t.Fatalf("Send() = %d, %v, want 1 attempt", attempts, err)
+	}
+
+	if got.Method != http.MethodPost || got.URL.Path != "/hooks/chat" {
+		t.Errorf("request = %s %s, want POST /hooks/chat", got.Method, got.URL.Path)
+	}
+	if string(body) != `{"format": "slack"}` {
+		t.Errorf("body = %s, want the slack payload", body)
+	}
+	headers := map[string]string{
+		"Authorization":         "Bearer s3cret",
+		"X-Plan-Status":         "critical (2)",
+		"Content-Type":          "application/json",
+		"User-Agent":            "InfraSync/1.0.0",
+		"X-Infrasync-Signature": Sign([]byte("signing-key"), body),
+	}
+	for name, want := range headers {
+		if v := got.Header.Get(name); v != want {
+			t.Errorf("%s = %q, want %q", name, v, want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// RFC 4231, test case 2
+	want := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got := Sign([]byte("Jefe"), []byte("what do ya want for nothing?")); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestWebhook_SendRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		wantErr  string
+	}{
+		{"success", []int{200}, 1, ""},
+		{"recovers", []int{503, 500, 204}, 3, ""},
+		{"rate limited", []int{429, 200}, 2, ""},
+		{"gives up", []int{502, 502, 502, 502}, 3, "502 Bad Gateway: failure 3"},
+		{"client error", []int{400, 200}, 1, "400 Bad Request: failure 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				status := tt.statuses[min(n, len(tt.statuses))-1]
+				w.WriteHeader(status)
+				if status >= 300 {
+					fmt.Fprintf(w, "failure %d\n", n)
+				}
+			}))
+			defer server.Close()
+
+			h := testWebhook(t, Config{URL: server.URL})
+			attempts, err := h.Send(context.Background(), testReport())
+			if attempts != tt.attempts || int(calls.Load()) != tt.attempts {
+				t.Errorf("Send() made %d attempts (server saw %d), want %d", attempts, calls.Load(), tt.attempts)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Send() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Send() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhook_SendBackoff(t *testing.T) {
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	h := testWebhook(t, Config{URL: server.URL, Attempts: 3, Backoff: 50 * time.Millisecond})
+	if _, err := h.Send(context.Background(), testReport()); err == nil {
+		t.Fatal("expected Send to fail")
+	}
+	if len(times) != 3 {
+		t.Fatalf("server saw %d attempts, want 3", len(times))
+	}
+	if d := times[1].Sub(times[0]); d < 50*time.Millisecond {
+		t.Errorf("first retry after %s, want at least 50ms", d)
+	}
+	if d := times[2].Sub(times[1]); d < 100*time.Millisecond {
+		t.Errorf("second retry after %s, want the backoff doubled", d)
+	}
+}
+
+func TestWebhook_SendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	h := testWebhook(t, Config{URL: server.URL, Attempts: 2, Timeout: 20 * time.Millisecond})
+	attempts, err := h.Send(context.Background(), testReport())
+	if attempts != 2 || err == nil || !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Errorf("Send() = %d, %v, want 2 attempts timing out", attempts, err)
+	}
+}
+
+func TestWebhook_SendCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	h := testWebhook(t, Config{URL: server.URL, Attempts: 10, Backoff: time.Minute})
+	start := time.Now()
+	if _, err := h.Send(ctx, testReport()); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("Send() error = %v, want the context error", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("Send() kept waiting after the context was done")
+	}
+}
+
+func TestWebhook_SendErrors(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_EMPTY", "")
+	tests := []struct {
+		name    string
+		cfg     Config
+		report  func(*Report)
+		wantErr string
+	}{
+		{"empty secret", Config{URL: "http://127.0.0.1:1", Secret: `{{ env "TEST_WEBHOOK_EMPTY" }}`}, nil, "secret is empty"},
+		{"empty url", Config{URL: `{{ env "TEST_WEBHOOK_EMPTY" }}`}, nil, "url is empty"},
+		{"missing field", Config{URL: "http://127.0.0.1:1/{{ .Nope }}"}, nil, "error rendering url"},
+		{"render", Config{URL: "http://127.0.0.1:1"}, func(r *Report) {
+			r.Render = func(io.Writer, string) error { return fmt.Errorf("unknown format") }
+		}, "error rendering json: unknown format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testReport()
+			if tt.report != nil {
+				tt.report(&r)
+			}
+			_, err := testWebhook(t, tt.cfg).Send(context.Background(), r)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Webhook URLs often embed a token, which must not end up in CI logs
+func TestWebhook_SendErrorsHideURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	for _, target := range []string{
+		server.URL + "/services/T0001/B0001/s3cr3t-t0ken",
+		"http://127.0.0.1:1/services/s3cr3t-t0ken\x7f",
+	} {
+		_, err := testWebhook(t, Config{URL: target, Attempts: 1}).Send(context.Background(), testReport())
+		if err == nil {
+			t.Fatalf("Send(%q) succeeded, want an error", target)
+		}
+		if strings.Contains(err.Error(), "s3cr3t-t0ken") {
+			t.Errorf("Send() error reveals the url: %v", err)
+		}
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"no name", Config{URL: "http://example.com"}, "webhook without name"},
+		{"no url", Config{Name: "a"}, "url is required"},
+		{"trigger", Config{Name: "a", URL: "http://example.com", When: []Trigger{"sometimes"}}, `unknown trigger "sometimes"`},
+		{"template", Config{Name: "a", URL: "http://example.com", Headers: map[string]string{"X-A": "{{ if }}"}}, "error parsing header X-A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhook_Triggered(t *testing.T) {
+	quiet := Report{}
+	changes := Report{Changes: true}
+	deletes := Report{Changes: true, Deletes: true}
+	critical := Report{Changes: true, Critical: true}
+
+	tests := []struct {
+		when []Trigger
+		want [4]bool // quiet, changes, deletes, critical
+	}{
+		{nil, [4]bool{true, true, true, true}},
+		{[]Trigger{TriggerAlways}, [4]bool{true, true, true, true}},
+		{[]Trigger{TriggerChanges}, [4]bool{false, true, true, true}},
+		{[]Trigger{TriggerDeletes}, [4]bool{false, false, true, false}},
+		{[]Trigger{TriggerCritical}, [4]bool{false, false, false, true}},
+		{[]Trigger{TriggerCritical, TriggerDeletes}, [4]bool{false, false, true, true}},
+	}
+
+	for _, tt := range tests {
+		h := testWebhook(t, Config{URL: "http://example.com", When: tt.when})
+		for i, r := range []Report{quiet, changes, deletes, critical} {
+			if got := h.Triggered(r); got != tt.want[i] {
+				t.Errorf("when %v: Triggered(report %d) = %v, want %v", tt.when, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	hooks, err := NewAll([]Config{
+		{Name: "broken", URL: server.URL + "/broken"},
+		{Name: "deletes", URL: server.URL + "/deletes", When: []Trigger{TriggerDeletes}},
+		{Name: "all", URL: server.URL + "/all"},
+	})
+	if err != nil {
+		t.Fatalf("NewAll failed: %v", err)
+	}
+
+	r := testReport()
+	r.Deletes = false
+	results := Notify(context.Background(), hooks, r)
+
+	if len(results) != 3 {
+		t.Fatalf("Notify() returned %d results, want 3", len(results))
+	}
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "webhook broken: 404") {
+		t.Errorf("broken: error = %v, want a 404 error", results[0].Err)
+	}
+	if !results[1].Skipped || results[1].Attempts != 0 {
+		t.Errorf("deletes: %+v, want skipped", results[1])
+	}
+	if results[2].Err != nil || results[2].Attempts != 1 {
+		t.Errorf("all: %+v, want delivered", results[2])
+	}
+	if strings.Join(paths, " ") != "/broken /all" {
+		t.Errorf("server saw %v, want /broken /all", paths)
+	}
+}
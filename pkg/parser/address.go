@@ -0,0 +1,58 @@
+package parser
+
+import "strings"
+
+// ModuleAddress returns the module part of a resource address, e.g.
+// "module.vpc.module.subnets" for "module.vpc.module.subnets.aws_subnet.a".
+// Resources in the root module return an empty string.
+func ModuleAddress(address string) string {
+	return strings.Join(ModuleCalls(address), ".")
+}
+
+// ModuleCalls returns the module calls leading to a resource, outermost
+// first, e.g. ["module.vpc", "module.subnets"] for
+// "module.vpc.module.subnets.aws_subnet.a"
+func ModuleCalls(address string) []string {
+	parts := splitAddress(address)
+	calls := make([]string, 0)
+	for i := 0; i+1 < len(parts); i += 2 {
+		if parts[i] != "module" {
+			break
+		}
+		calls = append(calls, parts[i]+"."+parts[i+1])
+	}
+	return calls
+}
+
+// splitAddress splits an address on dots that are not inside an index,
+// so that `module.app["a.b"].aws_instance.web` keeps its for_each key intact.
+func splitAddress(address string) []string {
+	parts := make([]string, 0)
+	depth := 0
+	inString := false
+	start := 0
+	for i := 0; i < len(address); i++ {
+		switch c := address[i]; {
+		case c == '"' && (i == 0 || address[i-1] != '\\'):
+			inString = !inString
+		case inString:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '.' && depth == 0:
+			parts = append(parts, address[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, address[start:])
+}
+
+// Module returns the module address of the resource, as reported by
+// Terraform or parsed from its address
+func (c ResourceChange) Module() string {
+	if c.ModuleAddress != "" {
+		return c.ModuleAddress
+	}
+	return ModuleAddress(c.Address)
+}
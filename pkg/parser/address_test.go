@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModuleAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"aws_instance.web", ""},
+		{"data.aws_ami.ubuntu", ""},
+		{"module.vpc.aws_subnet.a", "module.vpc"},
+		{"module.vpc.module.subnets.aws_subnet.a[0]", "module.vpc.module.subnets"},
+		{`module.app["eu.west"].aws_instance.web`, `module.app["eu.west"]`},
+		{"module.app[1].aws_instance.web", "module.app[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			result := ModuleAddress(tt.address)
+			if result != tt.expected {
+				t.Errorf("ModuleAddress(%s) = %q, want %q", tt.address, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestModuleCalls(t *testing.T) {
+	got := ModuleCalls(`module.vpc.module.subnets["a.b"].aws_subnet.a`)
+	want := []string{"module.vpc", `module.subnets["a.b"]`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ModuleCalls() = %v, want %v", got, want)
+	}
+}
+
+func TestResourceChange_Module(t *testing.T) {
+	c := ResourceChange{Address: "module.vpc.aws_subnet.a"}
+	if got := c.Module(); got != "module.vpc" {
+		t.Errorf("Module() = %q, want module.vpc", got)
+	}
+
+	c.ModuleAddress = "module.network"
+	if got := c.Module(); got != "module.network" {
+		t.Errorf("Module() = %q, want the module address from the plan", got)
+	}
+}
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Actions accepted by Filter.Actions
+var filterActions = []string{"create", "update", "replace", "delete", "destroy", "read", "no-op"}
+
+// Filter narrows a plan down to some of its resources. Values within a
+// field are alternatives; all non-empty fields have to match.
+type Filter struct {
+	// Include and Exclude are address globs where * matches any sequence
+	// and ? a single character, e.g. "module.vpc.*" or "aws_instance.web[*]"
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Types are resource type globs, e.g. "aws_s3_*"
+	Types []string `json:"types,omitempty"`
+
+	// Actions are create, update, replace, delete (or destroy), read or
+	// no-op
+	Actions []string `json:"actions,omitempty"`
+
+	// Modules match resources in the module and its nested modules,
+	// including all instances of a module with count or for_each
+	Modules []string `json:"modules,omitempty"`
+}
+
+// IsZero reports whether the filter keeps every resource
+func (f Filter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.Types) == 0 &&
+		len(f.Actions) == 0 && len(f.Modules) == 0
+}
+
+// Validate checks the actions of the filter
+func (f Filter) Validate() error {
+	for _, a := range f.Actions {
+		if !slices.Contains(filterActions, a) {
+			return fmt.Errorf("unknown action %q (supported: %s)", a, strings.Join(filterActions, ", "))
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the filter keeps the resource
+func (f Filter) Matches(c ResourceChange) bool {
+	if len(f.Include) > 0 && !matchAnyGlob(f.Include, c.Address) {
+		return false
+	}
+	if matchAnyGlob(f.Exclude, c.Address) {
+		return false
+	}
+	if len(f.Types) > 0 && !matchAnyGlob(f.Types, c.Type) {
+		return false
+	}
+	if len(f.Actions) > 0 && !f.matchesAction(c) {
+		return false
+	}
+	if len(f.Modules) > 0 && !inAnyModule(f.Modules, c.Module()) {
+		return false
+	}
+	return true
+}
+
+func (f Filter) matchesAction(c ResourceChange) bool {
+	action := c.Action()
+	if action == "delete" && slices.Contains(f.Actions, "destroy") {
+		return true
+	}
+	return slices.Contains(f.Actions, action)
+}
+
+// ChangeCounts are the counters of a plan summary
+type ChangeCounts struct {
+	ToCreate  int `json:"to_create"`
+	ToUpdate  int `json:"to_update"`
+	ToDelete  int `json:"to_delete"`
+	ToReplace int `json:"to_replace"`
+	NoChanges int `json:"no_changes"`
+	ToRead    int `json:"to_read"`
+}
+
+// Changed returns the number of resources with changes
+func (c ChangeCounts) Changed() int {
+	return c.ToCreate + c.ToUpdate + c.ToDelete + c.ToReplace
+}
+
+// Counts returns the counters of the summary
+func (s *PlanSummary) Counts() ChangeCounts {
+	return ChangeCounts{
+		ToCreate:  s.ToCreate,
+		ToUpdate:  s.ToUpdate,
+		ToDelete:  s.ToDelete,
+		ToReplace: s.ToReplace,
+		NoChanges: s.NoChanges,
+		ToRead:    s.ToRead,
+	}
+}
+
+// Filter returns a copy of the summary with the resources kept by the
+// filter. The counters cover the kept resources; Total keeps the counters
+// of the whole plan.
+func (s *PlanSummary) Filter(f Filter) *PlanSummary {
+	if f.IsZero() {
+		return s
+	}
+
+	filtered := *s
+	filtered.Changes = make([]ResourceChange, 0)
+	filtered.DataSources = nil
+	filtered.ToCreate, filtered.ToUpdate, filtered.ToDelete, filtered.ToReplace, filtered.NoChanges, filtered.ToRead = 0, 0, 0, 0, 0, 0
+	if filtered.Total == nil {
+		total := s.Counts()
+		filtered.Total = &total
+	}
+
+	for _, c := range s.Changes {
+		if !f.Matches(c) {
+			continue
+		}
+		filtered.Changes = append(filtered.Changes, c)
+		filtered.count(c)
+	}
+	for _, c := range s.DataSources {
+		if !f.Matches(c) {
+			continue
+		}
+		filtered.DataSources = append(filtered.DataSources, c)
+		filtered.count(c)
+	}
+
+	return &filtered
+}
+
+// inAnyModule reports whether module is one of the given modules, an
+// instance of one of them or nested in one of them
+func inAnyModule(modules []string, module string) bool {
+	for _, m := range modules {
+		m = strings.TrimSuffix(m, ".")
+		if module == m || strings.HasPrefix(module, m+".") || strings.HasPrefix(module, m+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyGlob(globs []string, s string) bool {
+	for _, g := range globs {
+		if globRegexp(g).MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp compiles an address glob. Only * and ? are special, so
+// brackets and quotes of instance keys match literally.
+func globRegexp(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
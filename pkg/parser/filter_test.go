@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	web := ResourceChange{Address: "aws_instance.web[0]", Type: "aws_instance", IsCreate: true}
+	subnet := ResourceChange{Address: `module.vpc.module.subnets["a.b"].aws_subnet.private`, Type: "aws_subnet", IsDelete: true}
+	bucket := ResourceChange{Address: "module.vpc_logs.aws_s3_bucket.logs", ModuleAddress: "module.vpc_logs", Type: "aws_s3_bucket", IsUpdate: true}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []bool // web, subnet, bucket
+	}{
+		{"zero", Filter{}, []bool{true, true, true}},
+		{"include glob", Filter{Include: []string{"module.vpc.*"}}, []bool{false, true, false}},
+		{"include literal index", Filter{Include: []string{"aws_instance.web[0]"}}, []bool{true, false, false}},
+		{"exclude", Filter{Exclude: []string{"*.aws_s3_bucket.*", "aws_instance.web[?]"}}, []bool{false, true, false}},
+		{"type glob", Filter{Types: []string{"aws_s*"}}, []bool{false, true, true}},
+		{"action", Filter{Actions: []string{"create", "update"}}, []bool{true, false, true}},
+		{"destroy alias", Filter{Actions: []string{"destroy"}}, []bool{false, true, false}},
+		{"module and nested modules", Filter{Modules: []string{"module.vpc"}}, []bool{false, true, false}},
+		{"module instance", Filter{Modules: []string{"module.vpc.module.subnets"}}, []bool{false, true, false}},
+		{"all fields", Filter{Modules: []string{"module.vpc", "module.vpc_logs"}, Actions: []string{"update"}}, []bool{false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, c := range []ResourceChange{web, subnet, bucket} {
+				if got := tt.filter.Matches(c); got != tt.want[i] {
+					t.Errorf("Matches(%s) = %v, want %v", c.Address, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	if err := (Filter{Actions: []string{"create", "destroy", "no-op"}}).Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+	if err := (Filter{Actions: []string{"remove"}}).Validate(); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
+
+func TestPlanSummary_Filter(t *testing.T) {
+	summary := &PlanSummary{
+		Changes: []ResourceChange{
+			{Address: "aws_instance.a", IsCreate: true},
+			{Address: "aws_instance.b", IsDelete: true},
+			{Address: "aws_s3_bucket.c", IsUpdate: true},
+			{Address: "aws_s3_bucket.d", IsNoOp: true},
+		},
+		ToCreate: 1, ToDelete: 1, ToUpdate: 1, NoChanges: 1,
+	}
+
+	if got := summary.Filter(Filter{}); got != summary {
+		t.Error("Expected the zero filter to return the summary itself")
+	}
+
+	filtered := summary.Filter(Filter{Include: []string{"aws_instance.*"}})
+	if len(filtered.Changes) != 2 || filtered.ToCreate != 1 || filtered.ToDelete != 1 || filtered.ToUpdate != 0 || filtered.NoChanges != 0 {
+		t.Errorf("Unexpected filtered summary: %+v", filtered)
+	}
+	if filtered.Total == nil || filtered.Total.Changed() != 3 || filtered.Total.NoChanges != 1 {
+		t.Errorf("Total = %+v, want the counters of the whole plan", filtered.Total)
+	}
+	if len(summary.Changes) != 4 || summary.Total != nil {
+		t.Error("Filter modified the original summary")
+	}
+
+	// Filtering again narrows further but keeps the original totals
+	again := filtered.Filter(Filter{Actions: []string{"delete"}})
+	if len(again.Changes) != 1 || again.Total.Changed() != 3 {
+		t.Errorf("Unexpected summary after filtering twice: %+v", again)
+	}
+}
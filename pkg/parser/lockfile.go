@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	lockProviderPattern = regexp.MustCompile(`^provider\s+"([^"]+)"\s*\{`)
+	lockVersionPattern  = regexp.MustCompile(`^version\s*=\s*"([^"]+)"`)
+)
+
+// ParseLockFile reads the provider versions selected in a
+// .terraform.lock.hcl file, keyed by provider source address
+func ParseLockFile(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lock file: %w", err)
+	}
+
+	return parseLockFile(data), nil
+}
+
+// parseLockFile extracts provider versions from lock file contents. The
+// lock file is generated by Terraform with a fixed layout, so a line based
+// scan is enough and avoids pulling in a full HCL parser.
+func parseLockFile(data []byte) map[string]string {
+	versions := make(map[string]string)
+
+	provider := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := lockProviderPattern.FindStringSubmatch(line); m != nil {
+			provider = m[1]
+			continue
+		}
+		if provider == "" {
+			continue
+		}
+		if m := lockVersionPattern.FindStringSubmatch(line); m != nil {
+			versions[provider] = m[1]
+			provider = ""
+		}
+	}
+
+	return versions
+}
+
+// ApplyLockFile records the locked provider versions on the summary.
+// Providers only present in the lock file are added to the list.
+func (s *PlanSummary) ApplyLockFile(versions map[string]string) {
+	for source, version := range versions {
+		found := false
+		for i := range s.Providers {
+			if s.Providers[i].Source == source {
+				s.Providers[i].Version = version
+				found = true
+			}
+		}
+		if !found {
+			s.Providers = append(s.Providers, ProviderInfo{
+				Source:  source,
+				Name:    source[strings.LastIndex(source, "/")+1:],
+				Version: version,
+			})
+		}
+	}
+
+	sort.Slice(s.Providers, func(i, j int) bool { return s.Providers[i].Source < s.Providers[j].Source })
+}
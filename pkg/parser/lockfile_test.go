@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"testing"
+)
+
+const testLockFile = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+}
+`
+
+func TestParseLockFile(t *testing.T) {
+	versions := parseLockFile([]byte(testLockFile))
+
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 providers, got %d", len(versions))
+	}
+	if versions["registry.terraform.io/hashicorp/aws"] != "5.31.0" {
+		t.Errorf("Unexpected aws version: %s", versions["registry.terraform.io/hashicorp/aws"])
+	}
+	if versions["registry.terraform.io/hashicorp/random"] != "3.6.0" {
+		t.Errorf("Unexpected random version: %s", versions["registry.terraform.io/hashicorp/random"])
+	}
+}
+
+func TestApplyLockFile(t *testing.T) {
+	summary := &PlanSummary{
+		Providers: []ProviderInfo{
+			{Source: "registry.terraform.io/hashicorp/aws", Name: "aws", VersionConstraint: "~> 5.0"},
+		},
+	}
+
+	summary.ApplyLockFile(parseLockFile([]byte(testLockFile)))
+
+	if len(summary.Providers) != 2 {
+		t.Fatalf("Expected 2 providers, got %d", len(summary.Providers))
+	}
+	if summary.Providers[0].Version != "5.31.0" || summary.Providers[0].VersionConstraint != "~> 5.0" {
+		t.Errorf("Unexpected aws provider: %+v", summary.Providers[0])
+	}
+	if summary.Providers[1].Name != "random" || summary.Providers[1].Version != "3.6.0" {
+		t.Errorf("Unexpected random provider: %+v", summary.Providers[1])
+	}
+}
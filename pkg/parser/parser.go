@@ -4,37 +4,97 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	tfjson "github.com/hashicorp/terraform-json"
 )
 
 // ResourceChange represents a simplified resource change with action details
 type ResourceChange struct {
-	Address    string
-	Type       string
-	Actions    []string
-	Before     map[string]interface{}
-	After      map[string]interface{}
-	IsCreate   bool
-	IsUpdate   bool
-	IsDelete   bool
-	IsReplace  bool
-	IsNoOp     bool
-	BeforeSensitive interface{}
-	AfterSensitive  interface{}
-	AfterUnknown    interface{}
+	Address         string                 `json:"address"`
+	ModuleAddress   string                 `json:"module_address,omitempty"`
+	Mode            string                 `json:"mode,omitempty"` // managed or data
+	Type            string                 `json:"type"`
+	Name            string                 `json:"name,omitempty"`
+	ProviderName    string                 `json:"provider_name,omitempty"`
+	Actions         []string               `json:"actions"`
+	Before          map[string]interface{} `json:"before"`
+	After           map[string]interface{} `json:"after"`
+	IsCreate        bool                   `json:"is_create"`
+	IsUpdate        bool                   `json:"is_update"`
+	IsDelete        bool                   `json:"is_delete"`
+	IsReplace       bool                   `json:"is_replace"`
+	IsNoOp          bool                   `json:"is_no_op"`
+	IsRead          bool                   `json:"is_read,omitempty"`
+	BeforeSensitive interface{}            `json:"before_sensitive,omitempty"`
+	AfterSensitive  interface{}            `json:"after_sensitive,omitempty"`
+	AfterUnknown    interface{}            `json:"after_unknown,omitempty"`
+
+	// ReplacePaths are the attribute paths that force a replacement, each
+	// a list of attribute names and list indexes
+	ReplacePaths []interface{} `json:"replace_paths,omitempty"`
+}
+
+// Action names the action of a change: create, update, replace, delete,
+// read or no-op, or "" when no action is set
+func (c ResourceChange) Action() string {
+	switch {
+	case c.IsCreate:
+		return "create"
+	case c.IsUpdate:
+		return "update"
+	case c.IsReplace:
+		return "replace"
+	case c.IsDelete:
+		return "delete"
+	case c.IsRead:
+		return "read"
+	case c.IsNoOp:
+		return "no-op"
+	}
+	return ""
+}
+
+// IsDataSource reports whether the change is about a data resource
+func (c ResourceChange) IsDataSource() bool {
+	return c.Mode == string(tfjson.DataResourceMode)
+}
+
+// ProviderInfo describes a provider used by the plan
+type ProviderInfo struct {
+	Source            string `json:"source"` // e.g. registry.terraform.io/hashicorp/aws
+	Name              string `json:"name"`   // local name, e.g. aws
+	VersionConstraint string `json:"version_constraint,omitempty"`
+	Version           string `json:"version,omitempty"` // selected version, only known from a lock file
 }
 
 // PlanSummary contains the summary of terraform plan
 type PlanSummary struct {
-	TerraformVersion string
-	FormatVersion    string
-	Changes          []ResourceChange
-	ToCreate         int
-	ToUpdate         int
-	ToDelete         int
-	ToReplace        int
-	NoChanges        int
+	TerraformVersion string           `json:"terraform_version"`
+	FormatVersion    string           `json:"format_version"`
+	Providers        []ProviderInfo   `json:"providers"`
+	Changes          []ResourceChange `json:"changes"`
+	ToCreate         int              `json:"to_create"`
+	ToUpdate         int              `json:"to_update"`
+	ToDelete         int              `json:"to_delete"`
+	ToReplace        int              `json:"to_replace"`
+	NoChanges        int              `json:"no_changes"`
+
+	// DataSources are the data resources of the plan, kept apart from
+	// Changes. ToRead counts the ones Terraform can only read during apply
+	// because their configuration depends on values not known yet.
+	DataSources []ResourceChange `json:"data_sources,omitempty"`
+	ToRead      int              `json:"to_read"`
+
+	// Total holds the counters of the whole plan when the summary only
+	// covers the resources kept by a Filter, and is nil otherwise
+	Total *ChangeCounts `json:"total,omitempty"`
+
+	// Resource schema versions by type, from the prior state and from the
+	// planned values. A difference means the provider migrates the state.
+	PriorSchemaVersions   map[string]uint64 `json:"prior_schema_versions,omitempty"`
+	PlannedSchemaVersions map[string]uint64 `json:"planned_schema_versions,omitempty"`
 }
 
 // ParsePlanFile reads and parses a terraform plan JSON file
@@ -44,6 +104,11 @@ func ParsePlanFile(filename string) (*PlanSummary, error) {
 		return nil, fmt.Errorf("error reading plan file: %w", err)
 	}
 
+	return ParsePlanJSON(data)
+}
+
+// ParsePlanJSON parses the output of `terraform show -json`
+func ParsePlanJSON(data []byte) (*PlanSummary, error) {
 	var plan tfjson.Plan
 	if err := json.Unmarshal(data, &plan); err != nil {
 		return nil, fmt.Errorf("error unmarshalling plan JSON: %w", err)
@@ -57,44 +122,70 @@ func ParsePlan(plan *tfjson.Plan) (*PlanSummary, error) {
 	summary := &PlanSummary{
 		TerraformVersion: plan.TerraformVersion,
 		FormatVersion:    plan.FormatVersion,
+		Providers:        parseProviders(plan.Config),
 		Changes:          make([]ResourceChange, 0),
 	}
 
+	if plan.PriorState != nil && plan.PriorState.Values != nil {
+		summary.PriorSchemaVersions = schemaVersions(plan.PriorState.Values.RootModule)
+	}
+	if plan.PlannedValues != nil {
+		summary.PlannedSchemaVersions = schemaVersions(plan.PlannedValues.RootModule)
+	}
+
 	if plan.ResourceChanges == nil {
 		return summary, nil
 	}
 
 	for _, rc := range plan.ResourceChanges {
 		change := classifyChange(rc)
-		summary.Changes = append(summary.Changes, change)
-
-		// Count changes
-		if change.IsCreate {
-			summary.ToCreate++
-		}
-		if change.IsUpdate {
-			summary.ToUpdate++
-		}
-		if change.IsDelete {
-			summary.ToDelete++
-		}
-		if change.IsReplace {
-			summary.ToReplace++
-		}
-		if change.IsNoOp {
-			summary.NoChanges++
+		if change.IsDataSource() {
+			summary.DataSources = append(summary.DataSources, change)
+		} else {
+			summary.Changes = append(summary.Changes, change)
 		}
+		summary.count(change)
 	}
 
 	return summary, nil
 }
 
+// count adds a change to the counters. Data sources only count when they
+// are read during apply.
+func (s *PlanSummary) count(c ResourceChange) {
+	if c.IsDataSource() {
+		if c.IsRead {
+			s.ToRead++
+		}
+		return
+	}
+	if c.IsCreate {
+		s.ToCreate++
+	}
+	if c.IsUpdate {
+		s.ToUpdate++
+	}
+	if c.IsDelete {
+		s.ToDelete++
+	}
+	if c.IsReplace {
+		s.ToReplace++
+	}
+	if c.IsNoOp {
+		s.NoChanges++
+	}
+}
+
 // classifyChange determines the type of change for a resource
 func classifyChange(rc *tfjson.ResourceChange) ResourceChange {
 	change := ResourceChange{
-		Address: rc.Address,
-		Type:    rc.Type,
-		Actions: make([]string, len(rc.Change.Actions)),
+		Address:       rc.Address,
+		ModuleAddress: rc.ModuleAddress,
+		Mode:          string(rc.Mode),
+		Type:          rc.Type,
+		Name:          rc.Name,
+		ProviderName:  rc.ProviderName,
+		Actions:       make([]string, len(rc.Change.Actions)),
 	}
 
 	for i, action := range rc.Change.Actions {
@@ -107,6 +198,7 @@ func classifyChange(rc *tfjson.ResourceChange) ResourceChange {
 	change.BeforeSensitive = rc.Change.BeforeSensitive
 	change.AfterSensitive = rc.Change.AfterSensitive
 	change.AfterUnknown = rc.Change.AfterUnknown
+	change.ReplacePaths = rc.Change.ReplacePaths
 
 	// Classify the action
 	actions := rc.Change.Actions
@@ -140,12 +232,82 @@ func classifyChange(rc *tfjson.ResourceChange) ResourceChange {
 			change.IsUpdate = true
 		case tfjson.ActionNoop:
 			change.IsNoOp = true
+		case tfjson.ActionRead:
+			change.IsRead = true
 		}
 	}
 
 	return change
 }
 
+// parseProviders collects the providers configured in the plan, one entry
+// per source address regardless of how many aliases or modules use it
+func parseProviders(config *tfjson.Config) []ProviderInfo {
+	providers := make([]ProviderInfo, 0)
+	if config == nil {
+		return providers
+	}
+
+	keys := make([]string, 0, len(config.ProviderConfigs))
+	for k := range config.ProviderConfigs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	bySource := make(map[string]int)
+	for _, k := range keys {
+		pc := config.ProviderConfigs[k]
+		source := pc.FullName
+		if source == "" {
+			source = pc.Name
+		}
+
+		i, seen := bySource[source]
+		if !seen {
+			providers = append(providers, ProviderInfo{Source: source, Name: pc.Name})
+			i = len(providers) - 1
+			bySource[source] = i
+		}
+
+		if pc.VersionConstraint != "" && !strings.Contains(providers[i].VersionConstraint, pc.VersionConstraint) {
+			if providers[i].VersionConstraint != "" {
+				providers[i].VersionConstraint += ", "
+			}
+			providers[i].VersionConstraint += pc.VersionConstraint
+		}
+	}
+
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Source < providers[j].Source })
+	return providers
+}
+
+// schemaVersions returns the highest schema version of each managed
+// resource type in the module tree
+func schemaVersions(module *tfjson.StateModule) map[string]uint64 {
+	versions := make(map[string]uint64)
+
+	var walk func(m *tfjson.StateModule)
+	walk = func(m *tfjson.StateModule) {
+		if m == nil {
+			return
+		}
+		for _, r := range m.Resources {
+			if r.Mode != tfjson.ManagedResourceMode {
+				continue
+			}
+			if v, ok := versions[r.Type]; !ok || r.SchemaVersion > v {
+				versions[r.Type] = r.SchemaVersion
+			}
+		}
+		for _, child := range m.ChildModules {
+			walk(child)
+		}
+	}
+	walk(module)
+
+	return versions
+}
+
 // toMap safely converts interface{} to map[string]interface{}
 func toMap(v interface{}) map[string]interface{} {
 	if v == nil {
@@ -101,6 +101,96 @@ func TestClassifyChange_NoOp(t *testing.T) {
 	}
 }
 
+func TestClassifyChange_Read(t *testing.T) {
+	rc := &tfjson.ResourceChange{
+		Address: "data.aws_ami.ubuntu",
+		Mode:    tfjson.DataResourceMode,
+		Type:    "aws_ami",
+		Change: &tfjson.Change{
+			Actions: []tfjson.Action{tfjson.ActionRead},
+		},
+	}
+
+	change := classifyChange(rc)
+
+	if !change.IsRead || !change.IsDataSource() {
+		t.Error("Expected a data source read")
+	}
+	if change.IsCreate || change.IsUpdate || change.IsDelete || change.IsReplace || change.IsNoOp {
+		t.Error("Expected other flags to be false")
+	}
+}
+
+func TestResourceChange_Action(t *testing.T) {
+	tests := []struct {
+		actions []tfjson.Action
+		want    string
+	}{
+		{[]tfjson.Action{tfjson.ActionCreate}, "create"},
+		{[]tfjson.Action{tfjson.ActionUpdate}, "update"},
+		{[]tfjson.Action{tfjson.ActionDelete, tfjson.ActionCreate}, "replace"},
+		{[]tfjson.Action{tfjson.ActionCreate, tfjson.ActionDelete}, "replace"},
+		{[]tfjson.Action{tfjson.ActionDelete}, "delete"},
+		{[]tfjson.Action{tfjson.ActionRead}, "read"},
+		{[]tfjson.Action{tfjson.ActionNoop}, "no-op"},
+	}
+
+	for _, tt := range tests {
+		change := classifyChange(&tfjson.ResourceChange{Change: &tfjson.Change{Actions: tt.actions}})
+		if got := change.Action(); got != tt.want {
+			t.Errorf("Action() for %v = %q, want %q", tt.actions, got, tt.want)
+		}
+	}
+	if got := (ResourceChange{}).Action(); got != "" {
+		t.Errorf("Action() without flags = %q, want empty", got)
+	}
+}
+
+func TestParsePlan_DataSources(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{
+				Address: "aws_instance.web",
+				Mode:    tfjson.ManagedResourceMode,
+				Type:    "aws_instance",
+				Change:  &tfjson.Change{Actions: []tfjson.Action{tfjson.ActionUpdate}},
+			},
+			{
+				Address: "data.aws_ami.ubuntu",
+				Mode:    tfjson.DataResourceMode,
+				Type:    "aws_ami",
+				Change:  &tfjson.Change{Actions: []tfjson.Action{tfjson.ActionRead}},
+			},
+			{
+				Address: "data.aws_region.current",
+				Mode:    tfjson.DataResourceMode,
+				Type:    "aws_region",
+				Change:  &tfjson.Change{Actions: []tfjson.Action{tfjson.ActionNoop}},
+			},
+		},
+	}
+
+	summary, err := ParsePlan(plan)
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	if len(summary.Changes) != 1 || summary.Changes[0].Address != "aws_instance.web" {
+		t.Errorf("Expected only the managed resource in Changes, got %+v", summary.Changes)
+	}
+	if len(summary.DataSources) != 2 {
+		t.Errorf("Expected 2 data sources, got %d", len(summary.DataSources))
+	}
+	if summary.ToRead != 1 || summary.ToUpdate != 1 || summary.NoChanges != 0 {
+		t.Errorf("Unexpected counters: read=%d update=%d unchanged=%d", summary.ToRead, summary.ToUpdate, summary.NoChanges)
+	}
+
+	filtered := summary.Filter(Filter{Actions: []string{"read"}})
+	if len(filtered.Changes) != 0 || len(filtered.DataSources) != 1 || filtered.ToRead != 1 || filtered.Total.ToRead != 1 {
+		t.Errorf("Unexpected summary filtered on reads: %+v", filtered)
+	}
+}
+
 func TestParsePlan_CountsChanges(t *testing.T) {
 	plan := &tfjson.Plan{
 		TerraformVersion: "1.0.0",
@@ -189,3 +279,53 @@ func TestToMap_InvalidInput(t *testing.T) {
 		t.Error("Expected empty map for invalid input")
 	}
 }
+
+func TestParsePlan_ProvidersAndSchemaVersions(t *testing.T) {
+	plan := &tfjson.Plan{
+		Config: &tfjson.Config{
+			ProviderConfigs: map[string]*tfjson.ProviderConfig{
+				"aws":      {Name: "aws", FullName: "registry.terraform.io/hashicorp/aws", VersionConstraint: "~> 5.0"},
+				"aws.east": {Name: "aws", FullName: "registry.terraform.io/hashicorp/aws", Alias: "east"},
+			},
+		},
+		PriorState: &tfjson.State{
+			Values: &tfjson.StateValues{
+				RootModule: &tfjson.StateModule{
+					Resources: []*tfjson.StateResource{
+						{Type: "aws_instance", Mode: tfjson.ManagedResourceMode, SchemaVersion: 1},
+					},
+				},
+			},
+		},
+		PlannedValues: &tfjson.StateValues{
+			RootModule: &tfjson.StateModule{
+				ChildModules: []*tfjson.StateModule{
+					{
+						Resources: []*tfjson.StateResource{
+							{Type: "aws_instance", Mode: tfjson.ManagedResourceMode, SchemaVersion: 2},
+							{Type: "aws_ami", Mode: tfjson.DataResourceMode, SchemaVersion: 0},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	summary, err := ParsePlan(plan)
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	if len(summary.Providers) != 1 {
+		t.Fatalf("Expected aliases to be merged into 1 provider, got %d", len(summary.Providers))
+	}
+	if summary.Providers[0].VersionConstraint != "~> 5.0" {
+		t.Errorf("Unexpected version constraint: %q", summary.Providers[0].VersionConstraint)
+	}
+	if summary.PriorSchemaVersions["aws_instance"] != 1 || summary.PlannedSchemaVersions["aws_instance"] != 2 {
+		t.Errorf("Unexpected schema versions: prior=%v planned=%v", summary.PriorSchemaVersions, summary.PlannedSchemaVersions)
+	}
+	if _, ok := summary.PlannedSchemaVersions["aws_ami"]; ok {
+		t.Error("Expected data sources to be ignored")
+	}
+}
@@ -0,0 +1,182 @@
+package parser
+
+import "reflect"
+
+// ValueState tells apart the states an attribute can be in on one side of
+// a change
+type ValueState int
+
+const (
+	// ValueAbsent is an attribute that isn't set, e.g. before a create
+	ValueAbsent ValueState = iota
+	// ValueNull is an attribute set to null
+	ValueNull
+	// ValueKnown is an attribute with a known value
+	ValueKnown
+	// ValueUnknown is an attribute only known after apply
+	ValueUnknown
+)
+
+// Value is an attribute value before or after a change. Sensitive values
+// keep their raw value for comparisons but must never be displayed.
+type Value struct {
+	State     ValueState
+	Raw       interface{}
+	Sensitive bool
+}
+
+// IsKnown reports whether the value is known, null included
+func (v Value) IsKnown() bool {
+	return v.State == ValueKnown || v.State == ValueNull
+}
+
+// Bool returns the value as a bool, and whether it is a known bool
+func (v Value) Bool() (bool, bool) {
+	b, ok := v.Raw.(bool)
+	return b, ok && v.State == ValueKnown
+}
+
+// Number returns the value as a number, and whether it is a known number
+func (v Value) Number() (float64, bool) {
+	n, ok := v.Raw.(float64)
+	return n, ok && v.State == ValueKnown
+}
+
+// Equal reports whether both values are in the same state with the same
+// raw value. Sensitivity is ignored.
+func (v Value) Equal(other Value) bool {
+	return v.State == other.State && reflect.DeepEqual(v.Raw, other.Raw)
+}
+
+// BeforeValue returns the value of a top-level attribute before the change
+func (c ResourceChange) BeforeValue(key string) Value {
+	return attributeValue(c.Before, key, nil, c.BeforeSensitive)
+}
+
+// AfterValue returns the planned value of a top-level attribute
+func (c ResourceChange) AfterValue(key string) Value {
+	return attributeValue(c.After, key, c.AfterUnknown, c.AfterSensitive)
+}
+
+// UnknownAttributes returns the top-level attributes only known after
+// apply. Terraform leaves them out of the planned values.
+func (c ResourceChange) UnknownAttributes() []string {
+	keys := make([]string, 0)
+	if m, ok := c.AfterUnknown.(map[string]interface{}); ok {
+		for k := range m {
+			if marked(c.AfterUnknown, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+func attributeValue(values map[string]interface{}, key string, unknown, sensitive interface{}) Value {
+	v := Value{Sensitive: marked(sensitive, key)}
+
+	raw, exists := values[key]
+	switch {
+	case marked(unknown, key):
+		v.State = ValueUnknown
+	case !exists:
+		v.State = ValueAbsent
+	case raw == nil:
+		v.State = ValueNull
+	default:
+		v.State = ValueKnown
+		v.Raw = raw
+	}
+	return v
+}
+
+// marked reports whether key is marked true in an after_unknown or
+// sensitive value, which is either a map of marks or true for the whole
+// object
+func marked(marks interface{}, key string) bool {
+	switch m := marks.(type) {
+	case bool:
+		return m
+	case map[string]interface{}:
+		b, ok := m[key].(bool)
+		return ok && b
+	}
+	return false
+}
+
+// SensitiveValue replaces sensitive values in machine-readable output
+const SensitiveValue = "(sensitive)"
+
+// Masked returns a copy of the change with the values marked sensitive
+// replaced by SensitiveValue, at any depth
+func (c ResourceChange) Masked() ResourceChange {
+	c.Before = maskAttributes(c.Before, c.BeforeSensitive)
+	c.After = maskAttributes(c.After, c.AfterSensitive)
+	return c
+}
+
+// Masked returns a copy of the summary with the sensitive values of its
+// changes and data sources masked, for output
+func (s *PlanSummary) Masked() *PlanSummary {
+	copied := *s
+	copied.Changes = maskChanges(s.Changes)
+	copied.DataSources = maskChanges(s.DataSources)
+	return &copied
+}
+
+func maskChanges(changes []ResourceChange) []ResourceChange {
+	if changes == nil {
+		return nil
+	}
+	masked := make([]ResourceChange, len(changes))
+	for i, c := range changes {
+		masked[i] = c.Masked()
+	}
+	return masked
+}
+
+func maskAttributes(values map[string]interface{}, marks interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	masked := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		var m interface{}
+		switch marks := marks.(type) {
+		case bool:
+			m = marks
+		case map[string]interface{}:
+			m = marks[k]
+		}
+		masked[k] = mask(v, m)
+	}
+	return masked
+}
+
+// mask masks a value following its sensitive marks: true for the whole
+// value, or a map or list of marks for its elements
+func mask(v, marks interface{}) interface{} {
+	switch m := marks.(type) {
+	case bool:
+		if m && v != nil {
+			return SensitiveValue
+		}
+	case map[string]interface{}:
+		if obj, ok := v.(map[string]interface{}); ok {
+			return maskAttributes(obj, m)
+		}
+	case []interface{}:
+		if list, ok := v.([]interface{}); ok {
+			masked := make([]interface{}, len(list))
+			for i, elem := range list {
+				var elemMarks interface{}
+				if i < len(m) {
+					elemMarks = m[i]
+				}
+				masked[i] = mask(elem, elemMarks)
+			}
+			return masked
+		}
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResourceChange_Values(t *testing.T) {
+	c := ResourceChange{
+		Before:          map[string]interface{}{"name": "web", "password": "old", "endpoint": "db.example.com", "arn": "arn:1"},
+		After:           map[string]interface{}{"name": "web", "password": "new", "endpoint": nil, "tags": map[string]interface{}{}},
+		AfterUnknown:    map[string]interface{}{"arn": true, "id": true, "tags": map[string]interface{}{}, "size": false},
+		BeforeSensitive: map[string]interface{}{"password": true},
+		AfterSensitive:  map[string]interface{}{"password": true},
+	}
+
+	tests := []struct {
+		name      string
+		value     Value
+		state     ValueState
+		sensitive bool
+	}{
+		{"known", c.AfterValue("name"), ValueKnown, false},
+		{"null", c.AfterValue("endpoint"), ValueNull, false},
+		{"absent", c.AfterValue("missing"), ValueAbsent, false},
+		{"unknown", c.AfterValue("arn"), ValueUnknown, false},
+		{"unknown without planned value", c.AfterValue("id"), ValueUnknown, false},
+		{"nested unknowns are known at the top", c.AfterValue("tags"), ValueKnown, false},
+		{"sensitive", c.AfterValue("password"), ValueKnown, true},
+		{"sensitive before", c.BeforeValue("password"), ValueKnown, true},
+		{"before is never unknown", c.BeforeValue("arn"), ValueKnown, false},
+		{"absent before", c.BeforeValue("id"), ValueAbsent, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value.State != tt.state || tt.value.Sensitive != tt.sensitive {
+				t.Errorf("Value = %+v, want state %d, sensitive %v", tt.value, tt.state, tt.sensitive)
+			}
+		})
+	}
+
+	unknown := c.UnknownAttributes()
+	sort.Strings(unknown)
+	if len(unknown) != 2 || unknown[0] != "arn" || unknown[1] != "id" {
+		t.Errorf("UnknownAttributes() = %v, want [arn id]", unknown)
+	}
+}
+
+func TestValue_Accessors(t *testing.T) {
+	if b, ok := (Value{State: ValueKnown, Raw: true}).Bool(); !ok || !b {
+		t.Error("Expected a known bool")
+	}
+	if _, ok := (Value{State: ValueUnknown}).Bool(); ok {
+		t.Error("Expected an unknown value not to be a bool")
+	}
+	if n, ok := (Value{State: ValueKnown, Raw: float64(7)}).Number(); !ok || n != 7 {
+		t.Error("Expected a known number")
+	}
+	if !(Value{State: ValueNull}).IsKnown() || (Value{State: ValueUnknown}).IsKnown() {
+		t.Error("Expected null to be known and unknown not")
+	}
+	if (Value{State: ValueNull}).Equal(Value{State: ValueAbsent}) {
+		t.Error("Expected null and absent values to differ")
+	}
+	if !(Value{State: ValueKnown, Raw: "a", Sensitive: true}).Equal(Value{State: ValueKnown, Raw: "a"}) {
+		t.Error("Expected sensitivity to be ignored")
+	}
+}
+
+func TestResourceChange_Masked(t *testing.T) {
+	c := ResourceChange{
+		Before: map[string]interface{}{"name": "db", "password": "hunter2", "token": nil},
+		After: map[string]interface{}{
+			"name":     "db",
+			"password": "hunter3",
+			"settings": map[string]interface{}{"key": "secret", "size": 10.0},
+			"users":    []interface{}{map[string]interface{}{"name": "admin", "password": "pw"}},
+		},
+		BeforeSensitive: map[string]interface{}{"password": true, "token": true},
+		AfterSensitive: map[string]interface{}{
+			"password": true,
+			"settings": map[string]interface{}{"key": true},
+			"users":    []interface{}{map[string]interface{}{"password": true}},
+		},
+	}
+
+	masked := c.Masked()
+	want := ResourceChange{
+		Before: map[string]interface{}{"name": "db", "password": SensitiveValue, "token": nil},
+		After: map[string]interface{}{
+			"name":     "db",
+			"password": SensitiveValue,
+			"settings": map[string]interface{}{"key": SensitiveValue, "size": 10.0},
+			"users":    []interface{}{map[string]interface{}{"name": "admin", "password": SensitiveValue}},
+		},
+		BeforeSensitive: c.BeforeSensitive,
+		AfterSensitive:  c.AfterSensitive,
+	}
+	if !reflect.DeepEqual(masked, want) {
+		t.Errorf("Masked() = %+v, want %+v", masked, want)
+	}
+	if c.After["password"] != "hunter3" || c.After["settings"].(map[string]interface{})["key"] != "secret" {
+		t.Error("Masked() modified the original change")
+	}
+
+	// A whole object marked sensitive
+	c = ResourceChange{After: map[string]interface{}{"a": "x", "b": 1.0}, AfterSensitive: true}
+	if got := c.Masked().After; got["a"] != SensitiveValue || got["b"] != SensitiveValue {
+		t.Errorf("Masked() = %v, want every attribute masked", got)
+	}
+}
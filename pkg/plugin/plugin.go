@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// ProtocolVersion is the plugin protocol version spoken by this build.
+// A plugin must answer with a version listed in SupportedVersions.
+const ProtocolVersion = 1
+
+// SupportedVersions lists the protocol versions accepted from plugins
+var SupportedVersions = []int{1}
+
+// DefaultTimeout bounds a plugin run when its config has no timeout
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseSize limits how much plugin output is read
+const maxResponseSize = 16 << 20
+
+// Config declares an external analyzer executable
+type Config struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// Request is written as JSON to the plugin's stdin
+type Request struct {
+	ProtocolVersion  int                 `json:"protocol_version"`
+	InfraSyncVersion string              `json:"infrasync_version"`
+	Summary          *parser.PlanSummary `json:"summary"`
+}
+
+// Response is read as JSON from the plugin's stdout
+type Response struct {
+	ProtocolVersion int                `json:"protocol_version"`
+	Warnings        []analyzer.Warning `json:"warnings"`
+}
+
+// Plugin is a configured external analyzer
+type Plugin struct {
+	config Config
+}
+
+// New validates a plugin configuration
+func New(cfg Config) (*Plugin, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("plugin without name")
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("plugin %s: command is required", cfg.Name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Plugin{config: cfg}, nil
+}
+
+// NewAll validates every plugin configuration
+func NewAll(configs []Config) ([]*Plugin, error) {
+	plugins := make([]*Plugin, 0, len(configs))
+	for _, cfg := range configs {
+		p, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// Name returns the plugin name
+func (p *Plugin) Name() string {
+	return p.config.Name
+}
+
+// Run launches the plugin, sends it the summary and returns its warnings.
+// Warnings without a rule are attributed to the plugin by name.
+func (p *Plugin) Run(ctx context.Context, summary *parser.PlanSummary, infrasyncVersion string) ([]analyzer.Warning, error) {
+	request, err := json.Marshal(Request{
+		ProtocolVersion:  ProtocolVersion,
+		InfraSyncVersion: infrasyncVersion,
+		Summary:          summary,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: error encoding request: %w", p.config.Name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.config.Command, p.config.Args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("INFRASYNC_PLUGIN_PROTOCOL=%d", ProtocolVersion))
+	for k, v := range p.config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdin = bytes.NewReader(request)
+	// Don't wait forever on pipes held open by the plugin's children
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedWriter{w: &stdout, remaining: maxResponseSize}
+	cmd.Stderr = &limitedWriter{w: &stderr, remaining: 4096}
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("plugin %s: timed out after %s", p.config.Name, p.config.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %w: %s", p.config.Name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %w", p.config.Name, err)
+	}
+
+	var response Response
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", p.config.Name, err)
+	}
+	if !supported(response.ProtocolVersion) {
+		return nil, fmt.Errorf("plugin %s: unsupported protocol version %d (supported: %v)",
+			p.config.Name, response.ProtocolVersion, SupportedVersions)
+	}
+
+	for i, w := range response.Warnings {
+		switch w.Level {
+		case analyzer.RiskCritical, analyzer.RiskHigh, analyzer.RiskMedium, analyzer.RiskLow:
+		default:
+			return nil, fmt.Errorf("plugin %s: warning %d has unknown level %q", p.config.Name, i+1, w.Level)
+		}
+		if w.Rule == "" {
+			response.Warnings[i].Rule = p.config.Name
+		}
+	}
+
+	return response.Warnings, nil
+}
+
+// RunAll runs every plugin in order. A failing plugin doesn't stop the
+// others; its error is returned next to the warnings of the rest.
+func RunAll(ctx context.Context, plugins []*Plugin, summary *parser.PlanSummary, infrasyncVersion string) ([]analyzer.Warning, []error) {
+	warnings := make([]analyzer.Warning, 0)
+	errs := make([]error, 0)
+
+	for _, p := range plugins {
+		w, err := p.Run(ctx, summary, infrasyncVersion)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		warnings = append(warnings, w...)
+	}
+
+	return warnings, errs
+}
+
+func supported(version int) bool {
+	for _, v := range SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedWriter discards everything written after the limit is reached
+type limitedWriter struct {
+	w         io.Writer
+	remaining int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if l.remaining <= 0 {
+		return n, nil
+	}
+	if len(p) > l.remaining {
+		p = p[:l.remaining]
+	}
+	l.remaining -= len(p)
+	if _, err := l.w.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,142 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// TestMain lets the test binary act as a plugin when launched by the tests
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("INFRASYNC_TEST_PLUGIN"); mode != "" {
+		os.Exit(runTestPlugin(mode))
+	}
+	os.Exit(m.Run())
+}
+
+func runTestPlugin(mode string) int {
+	var request Request
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch mode {
+	case "echo":
+		response := Response{ProtocolVersion: request.ProtocolVersion}
+		for _, c := range request.Summary.Changes {
+			response.Warnings = append(response.Warnings, analyzer.Warning{
+				Level:    analyzer.RiskHigh,
+				Resource: c.Address,
+				Message:  "seen by plugin",
+			})
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(response)
+	case "future":
+		fmt.Println(`{"protocol_version": 99, "warnings": []}`)
+	case "garbage":
+		fmt.Println("not json")
+	case "crash":
+		fmt.Fprintln(os.Stderr, "boom")
+		return 3
+	case "hang":
+		time.Sleep(10 * time.Second)
+	}
+	_, _ = io.Copy(io.Discard, os.Stdin)
+	return 0
+}
+
+func testPlugin(t *testing.T, mode string, timeout time.Duration) *Plugin {
+	t.Helper()
+	p, err := New(Config{
+		Name:    "test-" + mode,
+		Command: os.Args[0],
+		Env:     map[string]string{"INFRASYNC_TEST_PLUGIN": mode},
+		Timeout: timeout,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return p
+}
+
+var testSummary = &parser.PlanSummary{
+	Changes: []parser.ResourceChange{{Address: "aws_instance.web", Type: "aws_instance", IsCreate: true}},
+}
+
+func TestRun_ReturnsWarnings(t *testing.T) {
+	warnings, err := testPlugin(t, "echo", 0).Run(context.Background(), testSummary, "test")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d", len(warnings))
+	}
+	if warnings[0].Resource != "aws_instance.web" || warnings[0].Rule != "test-echo" {
+		t.Errorf("Unexpected warning: %+v", warnings[0])
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		mode    string
+		timeout time.Duration
+		want    string
+	}{
+		{"future", 0, "unsupported protocol version 99"},
+		{"garbage", 0, "invalid response"},
+		{"crash", 0, "boom"},
+		{"hang", 200 * time.Millisecond, "timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			_, err := testPlugin(t, tt.mode, tt.timeout).Run(context.Background(), testSummary, "test")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunAll_IsolatesFailures(t *testing.T) {
+	plugins := []*Plugin{
+		testPlugin(t, "crash", 0),
+		testPlugin(t, "echo", 0),
+	}
+
+	warnings, errs := RunAll(context.Background(), plugins, testSummary, "test")
+
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Expected warnings from the working plugin, got %+v", warnings)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{Command: "x"}); err == nil {
+		t.Error("Expected error for missing name")
+	}
+	if _, err := New(Config{Name: "x"}); err == nil {
+		t.Error("Expected error for missing command")
+	}
+
+	p, err := New(Config{Name: "x", Command: "x"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.config.Timeout != DefaultTimeout {
+		t.Errorf("Expected default timeout, got %s", p.config.Timeout)
+	}
+}
@@ -0,0 +1,198 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"gopkg.in/yaml.v3"
+)
+
+// TestManifest lists policy test cases, each running the analysis on a
+// fixture plan and asserting which warnings are raised
+type TestManifest struct {
+	Tests []TestCase `yaml:"tests"`
+}
+
+// TestCase is a single fixture plan with its expected warnings. Plan paths
+// are relative to the manifest.
+type TestCase struct {
+	Name   string           `yaml:"name"`
+	Plan   string           `yaml:"plan"`
+	Expect []WarningMatcher `yaml:"expect"` // must be raised
+	Forbid []WarningMatcher `yaml:"forbid"` // must not be raised
+}
+
+// WarningMatcher matches warnings by rule ID, resource address and level.
+// Empty fields match anything.
+type WarningMatcher struct {
+	Rule     string `yaml:"rule"`
+	Resource string `yaml:"resource"`
+	Level    string `yaml:"level"`
+}
+
+// TestResult is the outcome of one test case
+type TestResult struct {
+	Name     string
+	Failures []string
+	Duration time.Duration
+}
+
+// Passed reports whether every assertion of the test case held
+func (r TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// AnalyzeFunc runs the analysis pipeline on a plan file
+type AnalyzeFunc func(planFile string) ([]analyzer.Warning, error)
+
+// LoadTestManifest reads a YAML test manifest
+func LoadTestManifest(filename string) (*TestManifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading test manifest: %w", err)
+	}
+
+	var manifest TestManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing test manifest %s: %w", filename, err)
+	}
+
+	for i, tc := range manifest.Tests {
+		if tc.Plan == "" {
+			return nil, fmt.Errorf("test %d (%s): plan is required", i+1, tc.Name)
+		}
+		if tc.Name == "" {
+			manifest.Tests[i].Name = tc.Plan
+		}
+	}
+
+	return &manifest, nil
+}
+
+// RunTests runs every test case of the manifest. Plan paths are resolved
+// relative to baseDir.
+func RunTests(manifest *TestManifest, baseDir string, analyze AnalyzeFunc) []TestResult {
+	results := make([]TestResult, 0, len(manifest.Tests))
+
+	for _, tc := range manifest.Tests {
+		start := time.Now()
+		result := TestResult{Name: tc.Name}
+
+		planFile := tc.Plan
+		if !filepath.IsAbs(planFile) {
+			planFile = filepath.Join(baseDir, planFile)
+		}
+
+		warnings, err := analyze(planFile)
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("analysis failed: %v", err))
+		} else {
+			result.Failures = append(result.Failures, checkExpectations(tc, warnings)...)
+		}
+
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func checkExpectations(tc TestCase, warnings []analyzer.Warning) []string {
+	failures := make([]string, 0)
+
+	for _, m := range tc.Expect {
+		if !anyMatch(m, warnings) {
+			failures = append(failures, fmt.Sprintf("expected warning %s was not raised", m))
+		}
+	}
+
+	for _, m := range tc.Forbid {
+		for _, w := range warnings {
+			if m.matches(w) {
+				failures = append(failures, fmt.Sprintf("forbidden warning %s was raised: %s", m, w.Message))
+			}
+		}
+	}
+
+	if len(failures) > 0 && len(warnings) > 0 {
+		raised := make([]string, 0, len(warnings))
+		for _, w := range warnings {
+			raised = append(raised, WarningMatcher{Rule: w.Rule, Resource: w.Resource, Level: string(w.Level)}.String())
+		}
+		failures = append(failures, "raised: "+strings.Join(raised, ", "))
+	}
+
+	return failures
+}
+
+func anyMatch(m WarningMatcher, warnings []analyzer.Warning) bool {
+	for _, w := range warnings {
+		if m.matches(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m WarningMatcher) matches(w analyzer.Warning) bool {
+	return (m.Rule == "" || m.Rule == w.Rule) &&
+		(m.Resource == "" || m.Resource == w.Resource) &&
+		(m.Level == "" || m.Level == string(w.Level))
+}
+
+func (m WarningMatcher) String() string {
+	parts := make([]string, 0, 3)
+	if m.Rule != "" {
+		parts = append(parts, "rule="+m.Rule)
+	}
+	if m.Resource != "" {
+		parts = append(parts, "resource="+m.Resource)
+	}
+	if m.Level != "" {
+		parts = append(parts, "level="+m.Level)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// PrintResults writes the results in the style of go test and reports
+// whether all tests passed. Passing tests are only listed when verbose.
+func PrintResults(w io.Writer, manifestName string, results []TestResult, verbose bool) bool {
+	passed := true
+	var total time.Duration
+
+	for _, r := range results {
+		total += r.Duration
+		if verbose {
+			fmt.Fprintf(w, "=== RUN   %s\n", r.Name)
+		}
+		if r.Passed() {
+			if verbose {
+				fmt.Fprintf(w, "--- PASS: %s (%.2fs)\n", r.Name, r.Duration.Seconds())
+			}
+			continue
+		}
+
+		passed = false
+		fmt.Fprintf(w, "--- FAIL: %s (%.2fs)\n", r.Name, r.Duration.Seconds())
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "    %s\n", f)
+		}
+	}
+
+	if passed {
+		if verbose {
+			fmt.Fprintln(w, "PASS")
+		}
+		fmt.Fprintf(w, "ok  \t%s\t%.3fs\n", manifestName, total.Seconds())
+	} else {
+		fmt.Fprintln(w, "FAIL")
+		fmt.Fprintf(w, "FAIL\t%s\t%.3fs\n", manifestName, total.Seconds())
+	}
+
+	return passed
+}
@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+)
+
+func fakeAnalyze(planFile string) ([]analyzer.Warning, error) {
+	if strings.HasSuffix(planFile, "broken.json") {
+		return nil, errors.New("invalid plan")
+	}
+	return []analyzer.Warning{
+		{Rule: "prod-instance-type", Resource: "aws_instance.prod_web", Level: analyzer.RiskHigh},
+	}, nil
+}
+
+func TestRunTests(t *testing.T) {
+	manifest := &TestManifest{
+		Tests: []TestCase{
+			{
+				Name:   "expected warning raised",
+				Plan:   "resize.json",
+				Expect: []WarningMatcher{{Rule: "prod-instance-type", Resource: "aws_instance.prod_web", Level: "high"}},
+			},
+			{
+				Name:   "wrong level",
+				Plan:   "resize.json",
+				Expect: []WarningMatcher{{Rule: "prod-instance-type", Level: "critical"}},
+			},
+			{
+				Name:   "forbidden warning raised",
+				Plan:   "resize.json",
+				Forbid: []WarningMatcher{{Rule: "prod-instance-type"}},
+			},
+			{
+				Name: "analysis error",
+				Plan: "broken.json",
+			},
+		},
+	}
+
+	results := RunTests(manifest, "fixtures", fakeAnalyze)
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	if !results[0].Passed() {
+		t.Errorf("Expected %q to pass: %v", results[0].Name, results[0].Failures)
+	}
+	for _, r := range results[1:] {
+		if r.Passed() {
+			t.Errorf("Expected %q to fail", r.Name)
+		}
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	results := []TestResult{
+		{Name: "passes"},
+		{Name: "fails", Failures: []string{"expected warning {rule=x} was not raised"}},
+	}
+
+	var buf bytes.Buffer
+	if PrintResults(&buf, "policy_tests.yml", results, false) {
+		t.Error("Expected PrintResults to report failure")
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "passes") {
+		t.Errorf("Expected passing tests to be hidden without verbose:\n%s", out)
+	}
+	if !strings.Contains(out, "--- FAIL: fails") || !strings.Contains(out, "FAIL\tpolicy_tests.yml") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+
+	buf.Reset()
+	if !PrintResults(&buf, "policy_tests.yml", results[:1], true) {
+		t.Error("Expected PrintResults to report success")
+	}
+	if !strings.Contains(buf.String(), "--- PASS: passes") || !strings.Contains(buf.String(), "ok  \tpolicy_tests.yml") {
+		t.Errorf("Unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestLoadTestManifest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy_tests.yml")
+	data := []byte(`
+tests:
+  - plan: fixtures/resize.json
+    expect:
+      - rule: prod-instance-type
+        resource: aws_instance.prod_web
+        level: high
+`)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := LoadTestManifest(file)
+	if err != nil {
+		t.Fatalf("LoadTestManifest failed: %v", err)
+	}
+	if len(manifest.Tests) != 1 || manifest.Tests[0].Name != "fixtures/resize.json" {
+		t.Errorf("Expected test named after its plan, got %+v", manifest.Tests)
+	}
+	if manifest.Tests[0].Expect[0].Level != "high" {
+		t.Errorf("Unexpected expectation: %+v", manifest.Tests[0].Expect[0])
+	}
+}
@@ -0,0 +1,188 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+// RuleConfig declares a custom rule. The rule applies to changes selected
+// by Match and raises a warning when Condition evaluates to true.
+type RuleConfig struct {
+	ID          string `yaml:"id"`
+	Match       Match  `yaml:"match"`
+	Condition   string `yaml:"condition"`
+	Severity    string `yaml:"severity"`
+	Message     string `yaml:"message"`
+	Explanation string `yaml:"explanation"`
+}
+
+// Match selects the changes a rule applies to. Types and addresses are
+// glob patterns as understood by path.Match. Empty lists match everything.
+type Match struct {
+	Types     []string `yaml:"types"`
+	Addresses []string `yaml:"addresses"`
+	Actions   []string `yaml:"actions"` // create, update, replace, delete, no-op
+}
+
+// Rule is a compiled custom rule
+type Rule struct {
+	config  RuleConfig
+	level   analyzer.RiskLevel
+	program *vm.Program
+}
+
+// Compile validates a rule configuration and compiles its condition. The
+// condition can use before, after and after_unknown (attribute maps),
+// address, type and action.
+func Compile(cfg RuleConfig) (*Rule, error) {
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("rule without id")
+	}
+	if cfg.Message == "" {
+		return nil, fmt.Errorf("rule %s: message is required", cfg.ID)
+	}
+
+	level := analyzer.RiskLevel(cfg.Severity)
+	switch level {
+	case analyzer.RiskCritical, analyzer.RiskHigh, analyzer.RiskMedium, analyzer.RiskLow:
+	default:
+		return nil, fmt.Errorf("rule %s: unknown severity %q", cfg.ID, cfg.Severity)
+	}
+
+	for _, pattern := range append(append([]string{}, cfg.Match.Types...), cfg.Match.Addresses...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid pattern %q: %w", cfg.ID, pattern, err)
+		}
+	}
+
+	rule := &Rule{config: cfg, level: level}
+	if cfg.Condition != "" {
+		program, err := expr.Compile(cfg.Condition, expr.Env(environment(parser.ResourceChange{})), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: invalid condition: %w", cfg.ID, err)
+		}
+		rule.program = program
+	}
+
+	return rule, nil
+}
+
+// CompileAll compiles every rule and returns them ready to be passed to
+// analyzer.AnalyzeChanges
+func CompileAll(configs []RuleConfig) ([]analyzer.Rule, error) {
+	rules := make([]analyzer.Rule, 0, len(configs))
+	seen := make(map[string]bool)
+
+	for _, cfg := range configs {
+		if seen[cfg.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", cfg.ID)
+		}
+		seen[cfg.ID] = true
+
+		rule, err := Compile(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ID returns the rule identifier
+func (r *Rule) ID() string {
+	return r.config.ID
+}
+
+// Check evaluates the rule against a change. A condition that fails at
+// runtime is reported as a high risk warning rather than silently passing.
+func (r *Rule) Check(change parser.ResourceChange) []analyzer.Warning {
+	if !r.matches(change) {
+		return nil
+	}
+
+	if r.program != nil {
+		result, err := expr.Run(r.program, environment(change))
+		if err != nil {
+			return []analyzer.Warning{{
+				Rule:        r.config.ID,
+				Level:       analyzer.RiskHigh,
+				Resource:    change.Address,
+				Type:        change.Type,
+				Message:     fmt.Sprintf("Policy rule %s could not be evaluated", r.config.ID),
+				Explanation: err.Error(),
+			}}
+		}
+		if fired, ok := result.(bool); !ok || !fired {
+			return nil
+		}
+	}
+
+	return []analyzer.Warning{{
+		Rule:        r.config.ID,
+		Level:       r.level,
+		Resource:    change.Address,
+		Type:        change.Type,
+		Message:     r.config.Message,
+		Explanation: r.config.Explanation,
+	}}
+}
+
+func (r *Rule) matches(change parser.ResourceChange) bool {
+	return matchAny(r.config.Match.Types, change.Type) &&
+		matchAny(r.config.Match.Addresses, change.Address) &&
+		containsAction(r.config.Match.Actions, change.Action())
+}
+
+func environment(change parser.ResourceChange) map[string]interface{} {
+	afterUnknown, _ := change.AfterUnknown.(map[string]interface{})
+	if afterUnknown == nil {
+		afterUnknown = map[string]interface{}{}
+	}
+	before := change.Before
+	if before == nil {
+		before = map[string]interface{}{}
+	}
+	after := change.After
+	if after == nil {
+		after = map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"address":       change.Address,
+		"type":          change.Type,
+		"action":        change.Action(),
+		"before":        before,
+		"after":         after,
+		"after_unknown": afterUnknown,
+	}
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAction(actions []string, action string) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/kvizadsaderah/infrasync/pkg/parser"
+)
+
+var instanceTypeRule = RuleConfig{
+	ID: "prod-instance-type",
+	Match: Match{
+		Types:     []string{"aws_instance"},
+		Addresses: []string{"*prod*"},
+		Actions:   []string{"update"},
+	},
+	Condition:   "before.instance_type != after.instance_type",
+	Severity:    "high",
+	Message:     "Instance type must not change in production",
+	Explanation: "Resize production instances in a maintenance window",
+}
+
+func instanceUpdate(address, beforeType, afterType string) parser.ResourceChange {
+	return parser.ResourceChange{
+		Address:  address,
+		Type:     "aws_instance",
+		IsUpdate: true,
+		Before:   map[string]interface{}{"instance_type": beforeType},
+		After:    map[string]interface{}{"instance_type": afterType},
+	}
+}
+
+func TestRule_Fires(t *testing.T) {
+	rule, err := Compile(instanceTypeRule)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	warnings := rule.Check(instanceUpdate("aws_instance.prod_web", "t3.small", "t3.large"))
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d", len(warnings))
+	}
+	w := warnings[0]
+	if w.Rule != "prod-instance-type" || w.Level != analyzer.RiskHigh || w.Resource != "aws_instance.prod_web" {
+		t.Errorf("Unexpected warning: %+v", w)
+	}
+}
+
+func TestRule_DoesNotFire(t *testing.T) {
+	rule, err := Compile(instanceTypeRule)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change parser.ResourceChange
+	}{
+		{"condition false", instanceUpdate("aws_instance.prod_web", "t3.small", "t3.small")},
+		{"address not matched", instanceUpdate("aws_instance.staging_web", "t3.small", "t3.large")},
+		{"action not matched", parser.ResourceChange{Address: "aws_instance.prod_web", Type: "aws_instance", IsCreate: true}},
+		{"type not matched", parser.ResourceChange{Address: "aws_eip.prod", Type: "aws_eip", IsUpdate: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if warnings := rule.Check(tt.change); len(warnings) != 0 {
+				t.Errorf("Expected no warnings, got %+v", warnings)
+			}
+		})
+	}
+}
+
+func TestRule_WithoutCondition(t *testing.T) {
+	rule, err := Compile(RuleConfig{
+		ID:       "no-iam-users",
+		Match:    Match{Types: []string{"aws_iam_user"}, Actions: []string{"create"}},
+		Severity: "medium",
+		Message:  "Use SSO instead of IAM users",
+	})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	warnings := rule.Check(parser.ResourceChange{Address: "aws_iam_user.bob", Type: "aws_iam_user", IsCreate: true})
+	if len(warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %d", len(warnings))
+	}
+}
+
+func TestRule_RunsInsideAnalyzeChanges(t *testing.T) {
+	rules, err := CompileAll([]RuleConfig{instanceTypeRule})
+	if err != nil {
+		t.Fatalf("CompileAll failed: %v", err)
+	}
+
+	summary := &parser.PlanSummary{
+		Changes: []parser.ResourceChange{instanceUpdate("aws_instance.prod_web", "t3.small", "t3.large")},
+	}
+
+	found := false
+	for _, w := range analyzer.AnalyzeChanges(summary, rules...) {
+		if w.Rule == "prod-instance-type" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected custom rule warning from AnalyzeChanges")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RuleConfig
+	}{
+		{"missing id", RuleConfig{Severity: "high", Message: "m"}},
+		{"missing message", RuleConfig{ID: "r", Severity: "high"}},
+		{"unknown severity", RuleConfig{ID: "r", Severity: "severe", Message: "m"}},
+		{"syntax error", RuleConfig{ID: "r", Severity: "high", Message: "m", Condition: "before.x ==="}},
+		{"not boolean", RuleConfig{ID: "r", Severity: "high", Message: "m", Condition: "address"}},
+		{"bad pattern", RuleConfig{ID: "r", Severity: "high", Message: "m", Match: Match{Types: []string{"aws_["}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.cfg); err == nil {
+				t.Error("Expected compile error")
+			}
+		})
+	}
+}
+
+func TestCompileAll_DuplicateID(t *testing.T) {
+	if _, err := CompileAll([]RuleConfig{instanceTypeRule, instanceTypeRule}); err == nil {
+		t.Error("Expected error for duplicate rule id")
+	}
+}
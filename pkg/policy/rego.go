@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// RegoConfig lists Rego policy files or directories to evaluate against
+// the raw plan JSON
+type RegoConfig struct {
+	Paths       []string `yaml:"paths"`
+	RegoVersion string   `yaml:"rego_version"` // v1 (default) or v0
+}
+
+// regoRuleLevels maps the rule names InfraSync evaluates in every policy
+// package to the risk level of the resulting warnings
+var regoRuleLevels = map[string]analyzer.RiskLevel{
+	"deny": analyzer.RiskCritical,
+	"warn": analyzer.RiskHigh,
+}
+
+// RegoPolicy is a set of loaded Rego policies, prepared for evaluation
+type RegoPolicy struct {
+	queries []regoQuery
+}
+
+type regoQuery struct {
+	ref   string // e.g. data.terraform.deny
+	rule  string // deny or warn
+	query rego.PreparedEvalQuery
+}
+
+// LoadRego parses the configured .rego files and prepares a query for
+// every deny and warn rule found. Files ending in _test.rego are skipped.
+func LoadRego(ctx context.Context, cfg RegoConfig) (*RegoPolicy, error) {
+	version := ast.RegoV1
+	switch cfg.RegoVersion {
+	case "", "v1":
+	case "v0":
+		version = ast.RegoV0
+	default:
+		return nil, fmt.Errorf("unknown rego_version %q", cfg.RegoVersion)
+	}
+
+	files, err := regoFiles(cfg.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]func(*rego.Rego), 0, len(files))
+	refs := make(map[string]string)
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading policy: %w", err)
+		}
+		module, err := ast.ParseModuleWithOpts(file, string(src), ast.ParserOptions{RegoVersion: version})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range module.Rules {
+			name := r.Head.Ref()[0].Value.String()
+			if _, ok := regoRuleLevels[name]; ok {
+				refs[module.Package.Path.String()+"."+name] = name
+			}
+		}
+		modules = append(modules, rego.Module(file, string(src)))
+	}
+
+	policy := &RegoPolicy{}
+	for _, ref := range sortedStrings(refs) {
+		options := append([]func(*rego.Rego){rego.Query(ref), rego.SetRegoVersion(version)}, modules...)
+		query, err := rego.New(options...).PrepareForEval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		policy.queries = append(policy.queries, regoQuery{ref: ref, rule: refs[ref], query: query})
+	}
+
+	return policy, nil
+}
+
+// Evaluate runs every deny and warn rule against the raw plan JSON and
+// converts the results into warnings. Results may be plain messages or
+// objects with msg, resource, severity, rule and explanation fields.
+func (p *RegoPolicy) Evaluate(ctx context.Context, planJSON []byte) ([]analyzer.Warning, error) {
+	var input interface{}
+	if err := json.Unmarshal(planJSON, &input); err != nil {
+		return nil, fmt.Errorf("error unmarshalling plan JSON: %w", err)
+	}
+
+	warnings := make([]analyzer.Warning, 0)
+	for _, q := range p.queries {
+		rs, err := q.query.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating %s: %w", q.ref, err)
+		}
+
+		for _, result := range rs {
+			for _, expression := range result.Expressions {
+				values, ok := expression.Value.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("%s must be a set of messages", q.ref)
+				}
+				for _, v := range values {
+					warnings = append(warnings, regoWarning(q, v))
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Rule != warnings[j].Rule {
+			return warnings[i].Rule < warnings[j].Rule
+		}
+		return warnings[i].Message < warnings[j].Message
+	})
+
+	return warnings, nil
+}
+
+func regoWarning(q regoQuery, value interface{}) analyzer.Warning {
+	w := analyzer.Warning{
+		Rule:     strings.TrimPrefix(q.ref, "data."),
+		Level:    regoRuleLevels[q.rule],
+		Resource: "plan",
+	}
+
+	switch v := value.(type) {
+	case string:
+		w.Message = v
+	case map[string]interface{}:
+		w.Message = firstString(v, "msg", "message")
+		if resource := firstString(v, "resource", "address"); resource != "" {
+			w.Resource = resource
+		}
+		if rule := firstString(v, "rule", "id"); rule != "" {
+			w.Rule = rule
+		}
+		w.Type = firstString(v, "type")
+		w.Explanation = firstString(v, "explanation", "details")
+		switch level := analyzer.RiskLevel(firstString(v, "severity")); level {
+		case analyzer.RiskCritical, analyzer.RiskHigh, analyzer.RiskMedium, analyzer.RiskLow:
+			w.Level = level
+		}
+	default:
+		w.Message = fmt.Sprintf("%v", v)
+	}
+
+	return w
+}
+
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// regoFiles expands files and directories into a sorted list of policies
+func regoFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(file, ".rego") || strings.HasSuffix(file, "_test.rego") {
+				return nil
+			}
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error loading policies: %w", err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func sortedStrings(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kvizadsaderah/infrasync/pkg/analyzer"
+)
+
+const regoTestPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_s3_bucket.logs", "type": "aws_s3_bucket",
+     "change": {"actions": ["delete"], "before": {"bucket": "logs"}, "after": null}},
+    {"address": "aws_instance.web", "type": "aws_instance",
+     "change": {"actions": ["create"], "before": null, "after": {"instance_type": "t2.micro"}}}
+  ]
+}`
+
+func TestRegoPolicy_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	p, err := LoadRego(ctx, RegoConfig{Paths: []string{"testdata/rego"}})
+	if err != nil {
+		t.Fatalf("LoadRego failed: %v", err)
+	}
+
+	warnings, err := p.Evaluate(ctx, []byte(regoTestPlan))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %d: %+v", len(warnings), warnings)
+	}
+
+	w := warnings[0]
+	if w.Rule != "no-t2" || w.Level != analyzer.RiskMedium || w.Resource != "aws_instance.web" {
+		t.Errorf("Unexpected warn result: %+v", w)
+	}
+
+	w = warnings[1]
+	if w.Rule != "terraform.deny" || w.Level != analyzer.RiskCritical || w.Resource != "plan" {
+		t.Errorf("Unexpected deny result: %+v", w)
+	}
+	if w.Message != "S3 bucket aws_s3_bucket.logs must not be deleted" {
+		t.Errorf("Unexpected message: %s", w.Message)
+	}
+}
+
+func TestRegoPolicy_NoViolations(t *testing.T) {
+	ctx := context.Background()
+	p, err := LoadRego(ctx, RegoConfig{Paths: []string{"testdata/rego/terraform.rego"}})
+	if err != nil {
+		t.Fatalf("LoadRego failed: %v", err)
+	}
+
+	warnings, err := p.Evaluate(ctx, []byte(`{"resource_changes": []}`))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
+}
+
+func TestLoadRego_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := LoadRego(ctx, RegoConfig{Paths: []string{"testdata/missing"}}); err == nil {
+		t.Error("Expected error for missing path")
+	}
+	if _, err := LoadRego(ctx, RegoConfig{Paths: []string{"testdata/rego"}, RegoVersion: "v2"}); err == nil {
+		t.Error("Expected error for unknown rego version")
+	}
+}
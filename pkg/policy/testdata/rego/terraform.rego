@@ -0,0 +1,20 @@
+package terraform
+
+deny contains msg if {
+	some rc in input.resource_changes
+	rc.type == "aws_s3_bucket"
+	"delete" in rc.change.actions
+	msg := sprintf("S3 bucket %s must not be deleted", [rc.address])
+}
+
+warn contains result if {
+	some rc in input.resource_changes
+	rc.type == "aws_instance"
+	rc.change.after.instance_type == "t2.micro"
+	result := {
+		"msg": "t2 instances are deprecated",
+		"resource": rc.address,
+		"severity": "medium",
+		"rule": "no-t2",
+	}
+}
@@ -0,0 +1,5 @@
+package terraform
+
+test_ignored if {
+	false
+}